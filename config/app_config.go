@@ -0,0 +1,197 @@
+package config
+
+import (
+	"log/slog"
+	"math"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Logging. LogLevel is debug, info, warn or error; LogFormat is text or json.
+var (
+	LogLevel  = envString("FORUM_LOG_LEVEL", "info")
+	LogFormat = envString("FORUM_LOG_FORMAT", "text")
+)
+
+// MaxCommentDepth is how deeply replies may be nested below a top-level
+// comment (depth 0). Override with FORUM_MAX_COMMENT_DEPTH.
+var MaxCommentDepth = envInt("FORUM_MAX_COMMENT_DEPTH", 5)
+
+// Session lifetimes. A session expires after the idle timeout without any
+// request, and in any case once the absolute timeout since login has passed.
+// "Remember me" logins use the longer pair. Values are Go durations such as
+// "30m" or "720h".
+var (
+	SessionIdleTimeout        = envDuration("FORUM_SESSION_IDLE_TIMEOUT", 24*time.Hour)
+	SessionAbsoluteTimeout    = envDuration("FORUM_SESSION_ABSOLUTE_TIMEOUT", 7*24*time.Hour)
+	RememberMeIdleTimeout     = envDuration("FORUM_REMEMBER_ME_IDLE_TIMEOUT", 30*24*time.Hour)
+	RememberMeAbsoluteTimeout = envDuration("FORUM_REMEMBER_ME_ABSOLUTE_TIMEOUT", 90*24*time.Hour)
+)
+
+// Login brute-force protection. After LoginMaxAttempts failed logins for one
+// account (or LoginIPMaxAttempts from one IP) within LoginAttemptWindow,
+// further logins are refused for LoginLockoutBase, doubling with each further
+// failure up to LoginLockoutMax.
+var (
+	LoginMaxAttempts   = envInt("FORUM_LOGIN_MAX_ATTEMPTS", 5)
+	LoginIPMaxAttempts = envInt("FORUM_LOGIN_IP_MAX_ATTEMPTS", 20)
+	LoginAttemptWindow = envDuration("FORUM_LOGIN_ATTEMPT_WINDOW", 15*time.Minute)
+	LoginLockoutBase   = envDuration("FORUM_LOGIN_LOCKOUT_BASE", time.Minute)
+	LoginLockoutMax    = envDuration("FORUM_LOGIN_LOCKOUT_MAX", time.Hour)
+)
+
+// RateLimit allows Requests requests per Window, with bursts of up to Requests
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Per-route rate limits, counted per user when logged in and per IP otherwise.
+// Override with e.g. FORUM_RATE_LIMIT_POSTS=10/1m.
+var (
+	RegisterRateLimit = envRateLimit("FORUM_RATE_LIMIT_REGISTER", RateLimit{5, 10 * time.Minute})
+	PostRateLimit     = envRateLimit("FORUM_RATE_LIMIT_POSTS", RateLimit{5, time.Minute})
+	CommentRateLimit  = envRateLimit("FORUM_RATE_LIMIT_COMMENTS", RateLimit{20, time.Minute})
+	ReactionRateLimit = envRateLimit("FORUM_RATE_LIMIT_REACTIONS", RateLimit{60, time.Minute})
+	SearchRateLimit   = envRateLimit("FORUM_RATE_LIMIT_SEARCH", RateLimit{30, time.Minute})
+	ReportRateLimit   = envRateLimit("FORUM_RATE_LIMIT_REPORTS", RateLimit{10, time.Hour})
+	// Requests that send mail: password reset and verification resend
+	MailRateLimit = envRateLimit("FORUM_RATE_LIMIT_MAIL", RateLimit{5, time.Hour})
+)
+
+// TrustedProxies lists the reverse proxies whose X-Forwarded-For and
+// Forwarded headers are believed when resolving client IPs. Set
+// FORUM_TRUSTED_PROXIES to a comma-separated list of CIDRs or addresses,
+// e.g. "127.0.0.1,10.0.0.0/8". Empty means no proxy is trusted.
+var TrustedProxies = envPrefixes("FORUM_TRUSTED_PROXIES")
+
+// Outgoing mail. With FORUM_SMTP_HOST set, mail is sent over SMTP; otherwise
+// messages are written to FORUM_MAIL_DIR and logged, for local testing.
+// Links in mails point at FORUM_PUBLIC_URL, the frontend.
+var (
+	SMTPHost     = os.Getenv("FORUM_SMTP_HOST")
+	SMTPPort     = envInt("FORUM_SMTP_PORT", 587)
+	SMTPUsername = os.Getenv("FORUM_SMTP_USERNAME")
+	SMTPPassword = os.Getenv("FORUM_SMTP_PASSWORD")
+	MailFrom     = envString("FORUM_MAIL_FROM", "forum@localhost")
+	MailDir      = envString("FORUM_MAIL_DIR", "mail")
+	PublicURL    = strings.TrimRight(envString("FORUM_PUBLIC_URL", "http://localhost:8081"), "/")
+)
+
+// PublicOrigin is the scheme and host of FORUM_PUBLIC_URL. It is the only
+// origin allowed by CORS and the CSRF origin check.
+var PublicOrigin = originOf(PublicURL)
+
+// Lifetimes of the single-use tokens sent by mail
+var (
+	PasswordResetTokenTTL     = envDuration("FORUM_PASSWORD_RESET_TTL", time.Hour)
+	EmailVerificationTokenTTL = envDuration("FORUM_EMAIL_VERIFICATION_TTL", 48*time.Hour)
+)
+
+// RequireEmailVerification refuses logins until the address has been
+// verified. Set FORUM_REQUIRE_EMAIL_VERIFICATION=1 to enable.
+var RequireEmailVerification = envInt("FORUM_REQUIRE_EMAIL_VERIFICATION", 0) == 1
+
+// Deleted posts and comments stay in the trash, where moderators can restore
+// them, for TrashRetention before they are purged for good. The purge runs
+// every TrashPurgeInterval.
+var (
+	TrashRetention     = envDuration("FORUM_TRASH_RETENTION", 30*24*time.Hour)
+	TrashPurgeInterval = envDuration("FORUM_TRASH_PURGE_INTERVAL", time.Hour)
+)
+
+// Password hashing. PasswordHashAlgorithm is "bcrypt" (default) or
+// "argon2id". Hashes made with another algorithm or other parameters keep
+// working and are upgraded the next time their owner logs in. Values outside
+// what the algorithms accept fall back to the defaults.
+var (
+	PasswordHashAlgorithm = envString("FORUM_PASSWORD_HASH", "bcrypt")
+	BcryptCost            = envIntRange("FORUM_BCRYPT_COST", 12, 4, 31)
+	Argon2Time            = envIntRange("FORUM_ARGON2_TIME", 3, 1, math.MaxInt32)
+	Argon2MemoryKiB       = envIntRange("FORUM_ARGON2_MEMORY_KIB", 64*1024, 8, math.MaxInt32)
+	Argon2Threads         = envIntRange("FORUM_ARGON2_THREADS", 2, 1, math.MaxUint8)
+)
+
+// envString reads a string from the environment, falling back to def when unset
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envInt reads an integer from the environment, falling back to def when unset or invalid
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return def
+}
+
+// envIntRange reads an integer between min and max from the environment,
+// falling back to def when unset, invalid or out of range
+func envIntRange(key string, def, min, max int) int {
+	v := envInt(key, def)
+	if v < min || v > max {
+		slog.Warn("Ignoring out of range value", "variable", key, "value", v, "min", min, "max", max)
+		return def
+	}
+	return v
+}
+
+// envDuration reads a positive duration from the environment, falling back to def when unset or invalid
+func envDuration(key string, def time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// envRateLimit reads a rate limit written as "<requests>/<window>", falling back to def when unset or invalid
+func envRateLimit(key string, def RateLimit) RateLimit {
+	requests, window, ok := strings.Cut(os.Getenv(key), "/")
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(requests)
+	d, derr := time.ParseDuration(window)
+	if err != nil || derr != nil || n <= 0 || d <= 0 {
+		return def
+	}
+	return RateLimit{Requests: n, Window: d}
+}
+
+// originOf returns the "scheme://host[:port]" part of a URL, or the URL itself
+// when it cannot be parsed
+func originOf(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		slog.Warn("Cannot derive an origin from URL", "url", raw)
+		return raw
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// envPrefixes reads a comma-separated list of CIDRs or single addresses,
+// skipping invalid entries with a warning
+func envPrefixes(key string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		} else {
+			slog.Warn("Ignoring invalid entry", "variable", key, "entry", entry)
+		}
+	}
+	return prefixes
+}
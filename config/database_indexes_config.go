@@ -8,4 +8,28 @@ const IdxCommentsPostID = `CREATE INDEX IF NOT EXISTS idx_comments_post_id ON co
 const IdxCommentsUserID = `CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments(user_id);`
 const IdxReactionsUserID = `CREATE INDEX IF NOT EXISTS idx_reactions_user_id ON reactions(user_id);`
 const IdxReactionsPostID = `CREATE INDEX IF NOT EXISTS idx_reactions_post_id ON reactions(post_id);`
-const IdxReactionsCommentID = `CREATE INDEX IF NOT EXISTS idx_reactions_comment_id ON reactions(comment_id);`
\ No newline at end of file
+const IdxReactionsCommentID = `CREATE INDEX IF NOT EXISTS idx_reactions_comment_id ON reactions(comment_id);`
+
+// One reaction per user and target. The reactions primary key cannot enforce
+// this because one of its target columns is always NULL.
+const IdxReactionsUserPost = `CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_user_post ON reactions(user_id, post_id) WHERE post_id IS NOT NULL;`
+const IdxReactionsUserComment = `CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_user_comment ON reactions(user_id, comment_id) WHERE comment_id IS NOT NULL;`
+const IdxCommentsParentID = `CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_comment_id);`
+const IdxSessionsUserID = `CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);`
+const IdxLoginLockoutsCreatedAt = `CREATE INDEX IF NOT EXISTS idx_login_lockouts_created_at ON login_lockouts(created_at);`
+const IdxUserTokensUserID = `CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens(user_id, purpose);`
+const IdxCategoryModeratorsUserID = `CREATE INDEX IF NOT EXISTS idx_category_moderators_user_id ON category_moderators(user_id);`
+const IdxReportsStatusTarget = `CREATE INDEX IF NOT EXISTS idx_reports_status_target ON reports(status, target_type, target_id);`
+const IdxModerationActionsTarget = `CREATE INDEX IF NOT EXISTS idx_moderation_actions_target ON moderation_actions(target_type, target_id);`
+const IdxModerationActionsUser = `CREATE INDEX IF NOT EXISTS idx_moderation_actions_user ON moderation_actions(target_user_id, created_at);`
+const IdxUserBansUserID = `CREATE INDEX IF NOT EXISTS idx_user_bans_user_id ON user_bans(user_id);`
+const IdxPostsDeletedAt = `CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts(deleted_at) WHERE deleted_at IS NOT NULL;`
+const IdxCommentsDeletedAt = `CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments(deleted_at) WHERE deleted_at IS NOT NULL;`
+const IdxAuditLogActor = `CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at);`
+const IdxAuditLogTarget = `CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);`
+const IdxPostCategoriesCategoryID = `CREATE INDEX IF NOT EXISTS idx_post_categories_category_id ON post_categories(category_id);`
+
+// Composite indexes backing keyset pagination on (created_at, id)
+const IdxPostsCreatedAt = `CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts(created_at, post_id);`
+const IdxPostsUserCreatedAt = `CREATE INDEX IF NOT EXISTS idx_posts_user_created_at ON posts(user_id, created_at, post_id);`
+const IdxCommentsPostCreatedAt = `CREATE INDEX IF NOT EXISTS idx_comments_post_created_at ON comments(post_id, created_at, comment_id);`
@@ -0,0 +1,90 @@
+package config
+
+// NormalizeTimestamps rewrites the timestamps that listings are paged by in
+// UTC with millisecond precision. Older rows mix the seed data's
+// 2025-05-26T14:30:00 with Go's local time and offset, whose text order is not
+// their time order.
+const NormalizeTimestamps = `
+        UPDATE posts SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', created_at), created_at);
+        UPDATE comments SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', created_at), created_at);
+        UPDATE reports SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', created_at), created_at);
+        UPDATE moderation_actions SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', created_at), created_at);`
+
+// Migrations upgrade databases created with an older schema. Entry i moves a
+// database from PRAGMA user_version i to i+1. Fresh databases are created with
+// the latest schema and start at len(Migrations). Only ever append to this list.
+var Migrations = []string{
+	// 1: posts can belong to several categories
+	CreatePostCategoriesTable + IdxPostCategoriesCategoryID + `
+        INSERT OR IGNORE INTO post_categories (post_id, category_id)
+        SELECT post_id, category_id FROM posts;`,
+	// 2: composite indexes for cursor pagination
+	IdxPostsCreatedAt + IdxPostsUserCreatedAt + IdxCommentsPostCreatedAt,
+	// 3: threaded comment replies
+	`ALTER TABLE comments ADD COLUMN parent_comment_id TEXT REFERENCES comments(comment_id) ON DELETE CASCADE;
+        ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;` + IdxCommentsParentID,
+	// 4: several sessions per user; sessions keyed by user_id are rebuilt
+	`ALTER TABLE sessions RENAME TO sessions_old;
+        CREATE TABLE sessions (
+            id TEXT PRIMARY KEY,
+            session_id TEXT NOT NULL UNIQUE,
+            user_id TEXT NOT NULL,
+            ip_address TEXT,
+            user_agent TEXT,
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+        );
+        INSERT INTO sessions (id, session_id, user_id, ip_address, created_at, last_seen_at, expires_at)
+        SELECT lower(hex(randomblob(16))), session_id, user_id, ip_address, created_at, created_at, expires_at
+        FROM sessions_old;
+        DROP TABLE sessions_old;` + IdxSessionsUserID,
+	// 5: only a digest of the session token is stored. Plaintext tokens cannot
+	// be converted, so existing sessions are dropped and users log in again.
+	`DELETE FROM sessions;
+        ALTER TABLE sessions RENAME COLUMN session_id TO token_hash;`,
+	// 6: sliding expiry and "remember me"; existing sessions keep their current expiry as the hard limit
+	`ALTER TABLE sessions ADD COLUMN remember_me INTEGER NOT NULL DEFAULT 0;
+        ALTER TABLE sessions ADD COLUMN absolute_expires_at TIMESTAMP;
+        UPDATE sessions SET absolute_expires_at = expires_at;`,
+	// 7: record of login lockouts
+	CreateLoginLockoutsTable + IdxLoginLockoutsCreatedAt,
+	// 8: password reset and email verification; existing accounts count as verified
+	`ALTER TABLE user ADD COLUMN email_verified_at TIMESTAMP;
+        UPDATE user SET email_verified_at = CURRENT_TIMESTAMP;` + CreateUserTokensTable + IdxUserTokensUserID,
+	// 9: user roles and per-category moderators
+	`ALTER TABLE user ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));` +
+		CreateCategoryModeratorsTable + IdxCategoryModeratorsUserID,
+	// 10: reports, moderation history and hidden content
+	`ALTER TABLE posts ADD COLUMN hidden_at TIMESTAMP;
+        ALTER TABLE comments ADD COLUMN hidden_at TIMESTAMP;` +
+		CreateModerationActionsTable + CreateReportsTable +
+		IdxReportsStatusTarget + IdxModerationActionsTarget + IdxModerationActionsUser,
+	// 11: bans and suspensions
+	CreateUserBansTable + IdxUserBansUserID,
+	// 12: soft delete; deleted posts and comments stay in the trash until purged
+	`ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMP;
+        ALTER TABLE posts ADD COLUMN deleted_by TEXT;
+        ALTER TABLE comments ADD COLUMN deleted_at TIMESTAMP;
+        ALTER TABLE comments ADD COLUMN deleted_by TEXT;` + IdxPostsDeletedAt + IdxCommentsDeletedAt,
+	// 13: append-only audit log
+	CreateAuditLogTable + CreateAuditLogTriggers + IdxAuditLogActor + IdxAuditLogTarget,
+	// 14: one reaction per user and target; duplicates keep the latest row
+	`DELETE FROM reactions WHERE post_id IS NOT NULL AND rowid NOT IN (
+            SELECT MAX(rowid) FROM reactions WHERE post_id IS NOT NULL GROUP BY user_id, post_id);
+        DELETE FROM reactions WHERE comment_id IS NOT NULL AND rowid NOT IN (
+            SELECT MAX(rowid) FROM reactions WHERE comment_id IS NOT NULL GROUP BY user_id, comment_id);` +
+		IdxReactionsUserPost + IdxReactionsUserComment,
+	// 15: sortable timestamps for keyset pagination
+	NormalizeTimestamps,
+	// 16: migration 6 left absolute_expires_at nullable; rebuild sessions as in
+	// the fresh schema, with the current expiry as the hard limit where it is missing
+	`ALTER TABLE sessions RENAME TO sessions_old;` + CreateSessionsTable + `
+        INSERT INTO sessions (id, token_hash, user_id, ip_address, user_agent, remember_me,
+            created_at, last_seen_at, expires_at, absolute_expires_at)
+        SELECT id, token_hash, user_id, ip_address, user_agent, remember_me,
+            created_at, last_seen_at, expires_at, COALESCE(absolute_expires_at, expires_at)
+        FROM sessions_old;
+        DROP TABLE sessions_old;` + IdxSessionsUserID,
+}
@@ -6,6 +6,8 @@ const CreateUserTable = `CREATE TABLE IF NOT EXISTS user (
             user_id TEXT PRIMARY KEY,
             username TEXT NOT NULL UNIQUE CHECK (LENGTH(username) <= 50),
             email TEXT NOT NULL UNIQUE CHECK (LENGTH(email) <= 100),
+            email_verified_at TIMESTAMP,
+            role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
             created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
         );`
 
@@ -15,12 +17,21 @@ const CreateUserAuthTable = `CREATE TABLE IF NOT EXISTS user_auth (
             FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
         );`
 
+// A user may hold several sessions, one per device. id is the public handle
+// used to list and revoke sessions; token_hash is the SHA-256 digest of the
+// secret cookie value, which itself is never stored. expires_at slides
+// forward while the session is used, up to absolute_expires_at.
 const CreateSessionsTable = `CREATE TABLE IF NOT EXISTS sessions (
-            user_id TEXT PRIMARY KEY,
-            session_id TEXT NOT NULL UNIQUE,
+            id TEXT PRIMARY KEY,
+            token_hash TEXT NOT NULL UNIQUE,
+            user_id TEXT NOT NULL,
             ip_address TEXT,
+            user_agent TEXT,
+            remember_me INTEGER NOT NULL DEFAULT 0,
             created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
             expires_at TIMESTAMP NOT NULL,
+            absolute_expires_at TIMESTAMP NOT NULL,
             FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
         );`
 
@@ -37,10 +48,23 @@ const CreatePostsTable = `CREATE TABLE IF NOT EXISTS posts (
             content TEXT NOT NULL CHECK (LENGTH(content) <= 2000),
             created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
             updated_at TIMESTAMP,
+            hidden_at TIMESTAMP,
+            deleted_at TIMESTAMP,
+            deleted_by TEXT,
             FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
             FOREIGN KEY (category_id) REFERENCES categories(category_id) ON DELETE CASCADE
         );`
 
+// CreatePostCategoriesTable links posts to every category they are listed under.
+// posts.category_id is kept as the post's primary category.
+const CreatePostCategoriesTable = `CREATE TABLE IF NOT EXISTS post_categories (
+            post_id TEXT NOT NULL,
+            category_id INTEGER NOT NULL,
+            PRIMARY KEY (post_id, category_id),
+            FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+            FOREIGN KEY (category_id) REFERENCES categories(category_id) ON DELETE CASCADE
+        );`
+
 const CreateCommentsTable = `CREATE TABLE IF NOT EXISTS comments (
             comment_id TEXT PRIMARY KEY,
             post_id TEXT NOT NULL,
@@ -48,6 +72,11 @@ const CreateCommentsTable = `CREATE TABLE IF NOT EXISTS comments (
             content TEXT NOT NULL CHECK (LENGTH(content) <= 1000),
             created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
             updated_at TIMESTAMP,
+            parent_comment_id TEXT REFERENCES comments(comment_id) ON DELETE CASCADE,
+            depth INTEGER NOT NULL DEFAULT 0,
+            hidden_at TIMESTAMP,
+            deleted_at TIMESTAMP,
+            deleted_by TEXT,
             FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
             FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
         );`
@@ -66,4 +95,104 @@ const CreateReactionsTable = `CREATE TABLE IF NOT EXISTS reactions (
                 (post_id IS NULL AND comment_id IS NOT NULL) OR
                 (post_id IS NOT NULL AND comment_id IS NULL)
             )
-        );`
\ No newline at end of file
+        );`
+
+// Lockouts triggered by repeated failed logins, kept for administrators.
+// scope is "account" (identifier is the email) or "ip".
+const CreateLoginLockoutsTable = `CREATE TABLE IF NOT EXISTS login_lockouts (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            scope TEXT NOT NULL CHECK (scope IN ('account', 'ip')),
+            identifier TEXT NOT NULL,
+            ip_address TEXT,
+            failed_attempts INTEGER NOT NULL,
+            locked_until TIMESTAMP NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );`
+
+// Single-use tokens sent by mail. Like sessions, only the SHA-256 digest of
+// the token is stored.
+const CreateUserTokensTable = `CREATE TABLE IF NOT EXISTS user_tokens (
+            token_hash TEXT PRIMARY KEY,
+            user_id TEXT NOT NULL,
+            purpose TEXT NOT NULL CHECK (purpose IN ('password_reset', 'email_verification')),
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+        );`
+
+// Categories a moderator is responsible for
+const CreateCategoryModeratorsTable = `CREATE TABLE IF NOT EXISTS category_moderators (
+            category_id INTEGER NOT NULL,
+            user_id TEXT NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (category_id, user_id),
+            FOREIGN KEY (category_id) REFERENCES categories(category_id) ON DELETE CASCADE,
+            FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+        );`
+
+// Reports of abusive posts and comments. A user can report an item once; the
+// report stays in the table after it is resolved, pointing at the action that
+// resolved it.
+const CreateReportsTable = `CREATE TABLE IF NOT EXISTS reports (
+            report_id TEXT PRIMARY KEY,
+            reporter_id TEXT NOT NULL,
+            target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')),
+            target_id TEXT NOT NULL,
+            reason TEXT NOT NULL CHECK (LENGTH(reason) BETWEEN 1 AND 500),
+            status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            resolved_at TIMESTAMP,
+            action_id TEXT REFERENCES moderation_actions(action_id),
+            UNIQUE (reporter_id, target_type, target_id),
+            FOREIGN KEY (reporter_id) REFERENCES user(user_id) ON DELETE CASCADE
+        );`
+
+// History of moderator decisions. Rows are never changed or removed, and
+// have no foreign keys so that they outlive the content they are about.
+const CreateModerationActionsTable = `CREATE TABLE IF NOT EXISTS moderation_actions (
+            action_id TEXT PRIMARY KEY,
+            moderator_id TEXT NOT NULL,
+            action TEXT NOT NULL CHECK (action IN ('dismiss', 'hide', 'warn')),
+            target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')),
+            target_id TEXT NOT NULL,
+            target_user_id TEXT NOT NULL,
+            note TEXT NOT NULL DEFAULT '' CHECK (LENGTH(note) <= 500),
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );`
+
+// Bans stop a user from logging in, suspensions make the account read-only.
+// Both may expire; lifting one early keeps the row for the record.
+const CreateUserBansTable = `CREATE TABLE IF NOT EXISTS user_bans (
+            ban_id TEXT PRIMARY KEY,
+            user_id TEXT NOT NULL,
+            kind TEXT NOT NULL CHECK (kind IN ('ban', 'suspension')),
+            reason TEXT NOT NULL CHECK (LENGTH(reason) BETWEEN 1 AND 500),
+            issued_by TEXT NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP,
+            lifted_at TIMESTAMP,
+            lifted_by TEXT,
+            FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+        );`
+
+// Audit log of security and moderation events. Like moderation_actions it has
+// no foreign keys, so entries outlive the users and content they are about.
+const CreateAuditLogTable = `CREATE TABLE IF NOT EXISTS audit_log (
+            event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+            event TEXT NOT NULL,
+            actor_id TEXT,
+            target_type TEXT NOT NULL DEFAULT '',
+            target_id TEXT NOT NULL DEFAULT '',
+            ip_address TEXT NOT NULL DEFAULT '',
+            details TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        );`
+
+// CreateAuditLogTriggers makes the audit log append-only: entries can be
+// added but never changed or removed, not even by hand.
+const CreateAuditLogTriggers = `CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log BEGIN
+            SELECT RAISE(ABORT, 'audit_log is append-only');
+        END;
+        CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log BEGIN
+            SELECT RAISE(ABORT, 'audit_log is append-only');
+        END;`
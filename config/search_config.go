@@ -0,0 +1,61 @@
+package config
+
+// Full-text search over post titles, post content and comment content.
+// search_docs maps each indexed post or comment to the rowid it has in the
+// FTS5 table, so triggers can update single rows without scanning the index.
+// FTS5 is only compiled into mattn/go-sqlite3 with `-tags sqlite_fts5`.
+
+const CreateSearchDocsTable = `CREATE TABLE IF NOT EXISTS search_docs (
+            doc_id INTEGER PRIMARY KEY AUTOINCREMENT,
+            kind TEXT NOT NULL CHECK (kind IN ('post', 'comment')),
+            target_id TEXT NOT NULL,
+            post_id TEXT NOT NULL,
+            UNIQUE (kind, target_id)
+        );`
+
+const CreateSearchIndexTable = `CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+            title,
+            content,
+            tokenize = 'unicode61 remove_diacritics 2'
+        );`
+
+// SearchTriggers keep search_docs and search_index in sync with posts and comments
+var SearchTriggers = map[string]string{
+	"search_posts_ai": `CREATE TRIGGER IF NOT EXISTS search_posts_ai AFTER INSERT ON posts BEGIN
+            INSERT INTO search_docs (kind, target_id, post_id) VALUES ('post', new.post_id, new.post_id);
+            INSERT INTO search_index (rowid, title, content) VALUES (last_insert_rowid(), new.title, new.content);
+        END;`,
+	"search_posts_au": `CREATE TRIGGER IF NOT EXISTS search_posts_au AFTER UPDATE OF title, content ON posts BEGIN
+            UPDATE search_index SET title = new.title, content = new.content
+            WHERE rowid = (SELECT doc_id FROM search_docs WHERE kind = 'post' AND target_id = new.post_id);
+        END;`,
+	"search_posts_ad": `CREATE TRIGGER IF NOT EXISTS search_posts_ad AFTER DELETE ON posts BEGIN
+            DELETE FROM search_index WHERE rowid = (SELECT doc_id FROM search_docs WHERE kind = 'post' AND target_id = old.post_id);
+            DELETE FROM search_docs WHERE kind = 'post' AND target_id = old.post_id;
+        END;`,
+	"search_comments_ai": `CREATE TRIGGER IF NOT EXISTS search_comments_ai AFTER INSERT ON comments BEGIN
+            INSERT INTO search_docs (kind, target_id, post_id) VALUES ('comment', new.comment_id, new.post_id);
+            INSERT INTO search_index (rowid, title, content) VALUES (last_insert_rowid(), '', new.content);
+        END;`,
+	"search_comments_au": `CREATE TRIGGER IF NOT EXISTS search_comments_au AFTER UPDATE OF content ON comments BEGIN
+            UPDATE search_index SET content = new.content
+            WHERE rowid = (SELECT doc_id FROM search_docs WHERE kind = 'comment' AND target_id = new.comment_id);
+        END;`,
+	"search_comments_ad": `CREATE TRIGGER IF NOT EXISTS search_comments_ad AFTER DELETE ON comments BEGIN
+            DELETE FROM search_index WHERE rowid = (SELECT doc_id FROM search_docs WHERE kind = 'comment' AND target_id = old.comment_id);
+            DELETE FROM search_docs WHERE kind = 'comment' AND target_id = old.comment_id;
+        END;`,
+}
+
+// RebuildSearchIndex repopulates the index from scratch
+const RebuildSearchIndex = `
+        DELETE FROM search_index;
+        DELETE FROM search_docs;
+        INSERT INTO search_docs (kind, target_id, post_id)
+            SELECT 'post', post_id, post_id FROM posts;
+        INSERT INTO search_docs (kind, target_id, post_id)
+            SELECT 'comment', comment_id, post_id FROM comments;
+        INSERT INTO search_index (rowid, title, content)
+            SELECT d.doc_id, p.title, p.content FROM search_docs d JOIN posts p ON d.kind = 'post' AND p.post_id = d.target_id;
+        INSERT INTO search_index (rowid, title, content)
+            SELECT d.doc_id, '', c.content FROM search_docs d JOIN comments c ON d.kind = 'comment' AND c.comment_id = d.target_id;`
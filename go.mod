@@ -7,3 +7,5 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.24
 	golang.org/x/crypto v0.36.0
 )
+
+require golang.org/x/sys v0.31.0 // indirect
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"forum/config"
+	"forum/mailer"
+	"forum/middleware"
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+// AccountHandler handles password changes, password resets and email verification
+type AccountHandler struct {
+	UserRepo    *repository.UserRepository
+	SessionRepo *repository.SessionRepository
+	TokenRepo   *repository.TokenRepository
+	Mailer      mailer.Mailer
+	Audit       *AuditLogger
+}
+
+// NewAccountHandler creates a new AccountHandler
+func NewAccountHandler(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, tokenRepo *repository.TokenRepository, m mailer.Mailer, audit *AuditLogger) *AccountHandler {
+	return &AccountHandler{
+		UserRepo:    userRepo,
+		SessionRepo: sessionRepo,
+		TokenRepo:   tokenRepo,
+		Mailer:      m,
+		Audit:       audit,
+	}
+}
+
+// ChangePassword sets a new password for the authenticated user after checking
+// the current one, and logs out all of the user's other sessions
+func (h *AccountHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetCurrentUser(r)
+	session := middleware.GetCurrentSession(r)
+	if user == nil || session == nil {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.PasswordChange
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		utils.ErrorResponse(w, "Current and new password are required", http.StatusBadRequest)
+		return
+	}
+	if !utils.IsStrongPassword(req.NewPassword) {
+		utils.ErrorResponse(w, "Password must be at least 8 characters, with at least one letter and one digit", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.UserRepo.CheckPassword(user.ID, req.CurrentPassword); err != nil {
+		if err == repository.ErrInvalidCredentials {
+			utils.ErrorResponse(w, "Current password is incorrect", http.StatusForbidden)
+		} else {
+			utils.ErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.UserRepo.UpdatePassword(user.ID, req.NewPassword); err != nil {
+		utils.ErrorResponse(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditPasswordChange, TargetType: models.AuditTargetUser, TargetID: user.ID})
+
+	revoked, err := h.SessionRepo.DeleteOthers(user.ID, session.ID)
+	if err != nil {
+		utils.ErrorResponse(w, "Password changed, but other sessions could not be logged out", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{
+		"message":          "Password changed",
+		"revoked_sessions": revoked,
+	}, http.StatusOK)
+}
+
+// ForgotPassword mails a password reset link. The response is the same whether
+// or not the address is registered, so it cannot be used to probe for accounts;
+// the mail is sent in the background so that the response time does not tell either.
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if req.Email == "" {
+		utils.ErrorResponse(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.UserRepo.GetByEmail(req.Email)
+	if err == nil {
+		h.mailInBackground(user, "password reset", h.sendPasswordReset)
+	} else if err != repository.ErrUserNotFound {
+		slog.Error("Failed to look up user for password reset", "error", err)
+	}
+
+	utils.JSONResponse(w, map[string]string{
+		"message": "If this address is registered, a password reset link has been sent to it",
+	}, http.StatusAccepted)
+}
+
+// ResetPassword sets a new password using a token from a reset mail. All
+// sessions of the user are logged out.
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.PasswordReset
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" || req.NewPassword == "" {
+		utils.ErrorResponse(w, "Token and new password are required", http.StatusBadRequest)
+		return
+	}
+	if !utils.IsStrongPassword(req.NewPassword) {
+		utils.ErrorResponse(w, "Password must be at least 8 characters, with at least one letter and one digit", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.TokenRepo.Consume(req.Token, repository.TokenPasswordReset)
+	if err != nil {
+		if err == repository.ErrInvalidToken {
+			utils.ErrorResponse(w, "Invalid or expired reset token", http.StatusBadRequest)
+		} else {
+			utils.ErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.UserRepo.UpdatePassword(userID, req.NewPassword); err != nil {
+		utils.ErrorResponse(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditPasswordReset, ActorID: userID, TargetType: models.AuditTargetUser, TargetID: userID})
+
+	if _, err := h.SessionRepo.DeleteAllForUser(userID); err != nil {
+		slog.Error("Failed to revoke sessions after password reset", "user_id", userID, "error", err)
+	}
+	// The reset link reached the inbox, which proves the address as well
+	if err := h.UserRepo.MarkEmailVerified(userID); err != nil {
+		slog.Error("Failed to mark email verified after password reset", "user_id", userID, "error", err)
+	}
+
+	utils.JSONResponse(w, map[string]string{"message": "Password has been reset, please log in"}, http.StatusOK)
+}
+
+// VerifyEmail confirms the user's email address using a token from a verification mail
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		utils.ErrorResponse(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.TokenRepo.Consume(req.Token, repository.TokenEmailVerification)
+	if err != nil {
+		if err == repository.ErrInvalidToken {
+			utils.ErrorResponse(w, "Invalid or expired verification token", http.StatusBadRequest)
+		} else {
+			utils.ErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.UserRepo.MarkEmailVerified(userID); err != nil {
+		utils.ErrorResponse(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]string{"message": "Email address verified"}, http.StatusOK)
+}
+
+// ResendVerification mails a new verification link to the authenticated user
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetCurrentUser(r)
+	if user == nil {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if user.EmailVerified {
+		utils.ErrorResponse(w, "Email address is already verified", http.StatusConflict)
+		return
+	}
+
+	h.SendVerification(user)
+	utils.JSONResponse(w, map[string]string{"message": "Verification mail sent"}, http.StatusAccepted)
+}
+
+// SendVerification mails an email verification link to the user in the background
+func (h *AccountHandler) SendVerification(user *models.User) {
+	h.mailInBackground(user, "verification", h.sendVerification)
+}
+
+// mailInBackground sends an account mail without holding up the response, the
+// same for every account mail; a failure is logged and the user can ask again
+func (h *AccountHandler) mailInBackground(user *models.User, kind string, send func(*models.User) error) {
+	go func() {
+		if err := send(user); err != nil {
+			slog.Error("Failed to send account mail", "mail", kind, "user_id", user.ID, "error", err)
+		}
+	}()
+}
+
+func (h *AccountHandler) sendVerification(user *models.User) error {
+	token, err := h.TokenRepo.Create(user.ID, repository.TokenEmailVerification, config.EmailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+	return h.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening this link:\n\n%s\n\nThe link is valid for %s.\n",
+			user.Username, tokenLink("/verify-email", token), describeDuration(config.EmailVerificationTokenTTL)),
+	})
+}
+
+func (h *AccountHandler) sendPasswordReset(user *models.User) error {
+	token, err := h.TokenRepo.Create(user.ID, repository.TokenPasswordReset, config.PasswordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+	return h.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nSomeone asked to reset the password of your account. To choose a new password, open this link:\n\n%s\n\nThe link is valid for %s. If you did not ask for this, you can ignore this mail.\n",
+			user.Username, tokenLink("/reset-password", token), describeDuration(config.PasswordResetTokenTTL)),
+	})
+}
+
+// tokenLink builds a frontend link carrying a mailed token
+func tokenLink(path, token string) string {
+	return config.PublicURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// describeDuration writes a token lifetime the way a person would, e.g. "48 hours"
+func describeDuration(d time.Duration) string {
+	unit, n := "minute", int(d.Minutes())
+	if d >= time.Hour && d%time.Hour == 0 {
+		unit, n = "hour", int(d.Hours())
+	}
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
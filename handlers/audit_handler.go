@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+// AuditLogger is the single writer of the audit log. It stamps every event
+// with the client IP and, unless the event names one, the authenticated user
+// as actor. A failed write is logged but does not fail the request.
+type AuditLogger struct {
+	AuditRepo *repository.AuditRepository
+}
+
+// NewAuditLogger creates a new AuditLogger
+func NewAuditLogger(auditRepo *repository.AuditRepository) *AuditLogger {
+	return &AuditLogger{AuditRepo: auditRepo}
+}
+
+// Record appends an event caused by the request to the audit log
+func (a *AuditLogger) Record(r *http.Request, event models.AuditEvent) {
+	if event.ActorID == "" {
+		if user := middleware.GetCurrentUser(r); user != nil {
+			event.ActorID = user.ID
+		}
+	}
+	event.IPAddress = middleware.ClientIP(r)
+
+	if err := a.AuditRepo.Append(event); err != nil {
+		slog.Error("Failed to write audit log", "event", event.Event, "actor_id", event.ActorID, "error", err)
+	}
+}
+
+// AuditHandler lets administrators search the audit log
+type AuditHandler struct {
+	AuditRepo *repository.AuditRepository
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(auditRepo *repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{AuditRepo: auditRepo}
+}
+
+// AuditLogResponse is one page of the audit log
+type AuditLogResponse struct {
+	Events     []models.AuditEvent `json:"events"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// ListEvents lists audit log entries, newest first, filtered by ?event=,
+// ?actor_id=, ?target_type=&target_id=, ?ip= and the RFC 3339 times ?since=&until=
+func (h *AuditHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		Event:      query.Get("event"),
+		ActorID:    query.Get("actor_id"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+		IPAddress:  query.Get("ip"),
+	}
+	for _, bound := range []struct {
+		name string
+		dest **time.Time
+	}{
+		{"since", &filter.Since},
+		{"until", &filter.Until},
+	} {
+		raw := query.Get(bound.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			utils.ErrorResponse(w, "Invalid "+bound.name+", expected an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		*bound.dest = &t
+	}
+
+	events, next, err := h.AuditRepo.List(filter, page)
+	if err != nil {
+		if err == models.ErrInvalidCursor {
+			utils.ErrorResponse(w, "Invalid cursor", http.StatusBadRequest)
+		} else {
+			utils.ErrorResponse(w, "Failed to load audit log", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.JSONResponse(w, AuditLogResponse{Events: events, NextCursor: next.Encode()}, http.StatusOK)
+}
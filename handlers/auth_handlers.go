@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"forum/config"
+	"forum/middleware"
 	"forum/models"
 	"forum/repository"
 	"forum/utils"
@@ -13,15 +19,25 @@ import (
 
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
-	UserRepo    *repository.UserRepository
-	SessionRepo *repository.SessionRepository
+	UserRepo      *repository.UserRepository
+	SessionRepo   *repository.SessionRepository
+	CategoryRepo  *repository.CategoryRepository
+	BanRepo       *repository.BanRepository
+	Accounts      *AccountHandler // sends the verification mail after registration
+	LoginThrottle *middleware.LoginThrottle
+	Audit         *AuditLogger
 }
 
 // NewAuthHandler creates a new AuthHandler
-func NewAuthHandler(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository) *AuthHandler {
+func NewAuthHandler(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, categoryRepo *repository.CategoryRepository, banRepo *repository.BanRepository, accounts *AccountHandler, loginThrottle *middleware.LoginThrottle, audit *AuditLogger) *AuthHandler {
 	return &AuthHandler{
-		UserRepo:    userRepo,
-		SessionRepo: sessionRepo,
+		UserRepo:      userRepo,
+		SessionRepo:   sessionRepo,
+		CategoryRepo:  categoryRepo,
+		BanRepo:       banRepo,
+		Accounts:      accounts,
+		LoginThrottle: loginThrottle,
+		Audit:         audit,
 	}
 }
 
@@ -93,10 +109,16 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditRegister, ActorID: user.ID, TargetType: models.AuditTargetUser, TargetID: user.ID})
+
+	// The account exists either way; a lost mail can be sent again
+	h.Accounts.SendVerification(user)
+
 	response := map[string]interface{}{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
+		"id":             user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
 	}
 	utils.JSONResponse(w, response, http.StatusCreated)
 }
@@ -117,53 +139,100 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	login.Email = strings.TrimSpace(strings.ToLower(login.Email))
+
 	// Validate request
 	if login.Email == "" || login.Password == "" {
 		http.Error(w, "Email and password are required", http.StatusBadRequest)
 		return
 	}
 
+	// Refuse locked accounts and IPs before spending a password comparison
+	// Attempts refused during a lockout are not audited; the lockout itself is
+	if wait := h.LoginThrottle.Check(r, login.Email); wait > 0 {
+		tooManyLoginAttempts(w, wait)
+		return
+	}
+
 	// Authenticate user
 	user, err := h.UserRepo.Authenticate(login)
 
 	if err != nil {
 		if err == repository.ErrInvalidCredentials {
+			h.recordFailedLogin(r, login.Email, "", "invalid_credentials")
+			if wait := h.LoginThrottle.Failure(r, login.Email); wait > 0 {
+				h.Audit.Record(r, models.AuditEvent{Event: models.AuditLoginLockout,
+					Details: fmt.Sprintf("email=%s locked_for=%ds", login.Email, int(math.Ceil(wait.Seconds())))})
+				tooManyLoginAttempts(w, wait)
+				return
+			}
 			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 		} else {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
+	h.LoginThrottle.Success(login.Email)
+
+	if config.RequireEmailVerification && !user.EmailVerified {
+		h.recordFailedLogin(r, login.Email, user.ID, "email_not_verified")
+		utils.ErrorResponse(w, "Please verify your email address before logging in", http.StatusForbidden)
+		return
+	}
+
+	// Banned users may not sign in; suspended ones may, read-only
+	ban, err := h.BanRepo.GetActive(user.ID)
+	if err != nil && err != repository.ErrNotBanned {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if ban != nil && ban.Kind == models.BanKindBan {
+		h.recordFailedLogin(r, login.Email, user.ID, "banned")
+		utils.ErrorResponse(w, ban.Describe(), http.StatusForbidden)
+		return
+	}
 
 	// Create a new session
-	log.Println("Creating session for user:", user.ID)
-	session, err := h.SessionRepo.Create(user.ID, r.RemoteAddr)
-	log.Println("Session created:", session)
+	session, err := h.SessionRepo.Create(user.ID, middleware.ClientIP(r), r.UserAgent(), login.RememberMe)
 	if err != nil {
+		slog.Error("Failed to create session", "user_id", user.ID, "error", err)
 		http.Error(w, "Failed to create session", http.StatusInternalServerError)
 		return
 	}
+	slog.Info("User logged in", "session", session)
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditLogin, ActorID: user.ID, TargetType: models.AuditTargetUser, TargetID: user.ID,
+		Details: "session_id=" + session.ID})
 
 	// Set cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    session.SessionID,
-		Path:     "/",
-		Expires:  session.ExpiresAt,
-		HttpOnly: true,
-		Secure:   r.TLS != nil, // Set Secure flag if TLS is enabled
-		SameSite: http.SameSiteStrictMode,
-	})
+	middleware.SetSessionCookie(w, r, session)
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
 	response := models.LoginResponse{
 		User:      *user,
-		SessionID: session.SessionID,
+		CSRFToken: middleware.IssueCSRFToken(w, r),
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
+// recordFailedLogin adds a refused login to the audit log. userID is empty when
+// the credentials did not identify an account.
+func (h *AuthHandler) recordFailedLogin(r *http.Request, email, userID, reason string) {
+	event := models.AuditEvent{Event: models.AuditLoginFailed, Details: "email=" + email + " reason=" + reason}
+	if userID != "" {
+		event.TargetType = models.AuditTargetUser
+		event.TargetID = userID
+	}
+	h.Audit.Record(r, event)
+}
+
+// tooManyLoginAttempts answers a locked-out login with a Retry-After header
+func tooManyLoginAttempts(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	utils.ErrorResponse(w, fmt.Sprintf("Too many failed login attempts. Try again in %d seconds.", seconds), http.StatusTooManyRequests)
+}
+
 // Logout handles user logout
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	// Only allow POST requests
@@ -187,14 +256,13 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user := middleware.GetCurrentUser(r); user != nil {
+		h.Audit.Record(r, models.AuditEvent{Event: models.AuditLogout, ActorID: user.ID, TargetType: models.AuditTargetUser, TargetID: user.ID,
+			Details: "session_id=" + middleware.GetCurrentSession(r).ID})
+	}
+
 	// Clear the cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-	})
+	middleware.ClearSessionCookie(w)
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -220,5 +288,17 @@ func (h *AuthHandler) VerifySession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.JSONResponse(w, user, http.StatusOK)
+	response := models.VerifyResponse{
+		User:       *user,
+		Suspension: middleware.GetCurrentBan(r),
+		CSRFToken:  middleware.EnsureCSRFToken(w, r),
+	}
+	if user.HasRole(models.RoleModerator) {
+		response.ModeratedCategories, err = h.CategoryRepo.ModeratedCategoryIDs(user.ID)
+		if err != nil {
+			http.Error(w, "Failed to load moderated categories", http.StatusInternalServerError)
+			return
+		}
+	}
+	utils.JSONResponse(w, response, http.StatusOK)
 }
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTooManyLoginAttemptsSetsRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		wait time.Duration
+		want string
+	}{
+		{time.Minute, "60"},
+		{90*time.Second + time.Millisecond, "91"}, // rounded up, never early
+		{500 * time.Millisecond, "1"},
+	} {
+		rec := httptest.NewRecorder()
+		tooManyLoginAttempts(rec, tc.wait)
+		if rec.Code != http.StatusTooManyRequests {
+			t.Errorf("wait %v: got %d, want 429", tc.wait, rec.Code)
+		}
+		if got := rec.Header().Get("Retry-After"); got != tc.want {
+			t.Errorf("wait %v: Retry-After = %q, want %s", tc.wait, got, tc.want)
+		}
+	}
+}
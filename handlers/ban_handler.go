@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+const maxBanReasonLength = 500
+
+// BanHandler lets administrators ban and suspend users and lift those restrictions
+type BanHandler struct {
+	BanRepo     *repository.BanRepository
+	UserRepo    *repository.UserRepository
+	SessionRepo *repository.SessionRepository // banned users are signed out everywhere
+	Audit       *AuditLogger
+}
+
+// NewBanHandler creates a new BanHandler
+func NewBanHandler(banRepo *repository.BanRepository, userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, audit *AuditLogger) *BanHandler {
+	return &BanHandler{BanRepo: banRepo, UserRepo: userRepo, SessionRepo: sessionRepo, Audit: audit}
+}
+
+// UserBans lists (GET) the bans and suspensions of the user named in the URL or issues (POST) a new one
+func (h *BanHandler) UserBans(w http.ResponseWriter, r *http.Request) {
+	user, err := h.UserRepo.GetByID(r.PathValue("id"))
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			utils.ErrorResponse(w, "User not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to load user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		bans, err := h.BanRepo.ListByUser(user.ID)
+		if err != nil {
+			utils.ErrorResponse(w, "Failed to load bans", http.StatusInternalServerError)
+			return
+		}
+		utils.JSONResponse(w, map[string]interface{}{"bans": bans}, http.StatusOK)
+	case http.MethodPost:
+		h.issueBan(w, r, user)
+	default:
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// issueBan bans or suspends the user. A ban also ends all of the user's sessions.
+func (h *BanHandler) issueBan(w http.ResponseWriter, r *http.Request, user *models.User) {
+	var req struct {
+		Kind      string     `json:"kind"`
+		Reason    string     `json:"reason"`
+		ExpiresAt *time.Time `json:"expires_at"` // omit for a permanent restriction
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Kind != models.BanKindBan && req.Kind != models.BanKindSuspension {
+		utils.ErrorResponse(w, "Kind must be ban or suspension", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxBanReasonLength {
+		utils.ErrorResponse(w, fmt.Sprintf("A reason of at most %d characters is required", maxBanReasonLength), http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		utils.ErrorResponse(w, "The expiry must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	// An administrator has to be demoted first, so no one can lock themselves out
+	admin := middleware.GetCurrentUser(r)
+	if user.HasRole(models.RoleAdmin) {
+		utils.ErrorResponse(w, "Administrators cannot be banned or suspended", http.StatusConflict)
+		return
+	}
+
+	ban, err := h.BanRepo.Create(models.Ban{
+		UserID:    user.ID,
+		Kind:      req.Kind,
+		Reason:    req.Reason,
+		IssuedBy:  admin.ID,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to save ban", http.StatusInternalServerError)
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditBan, TargetType: models.AuditTargetUser, TargetID: user.ID,
+		Details: "kind=" + ban.Kind + " ban_id=" + ban.ID})
+
+	if ban.Kind == models.BanKindBan {
+		revoked, err := h.SessionRepo.DeleteAllForUser(user.ID)
+		if err != nil {
+			// Authenticate refuses the remaining sessions anyway
+			slog.Error("Failed to revoke sessions of banned user", "user_id", user.ID, "error", err)
+		} else {
+			slog.Info("Sessions revoked", "user_id", user.ID, "count", revoked)
+		}
+	}
+
+	utils.JSONResponse(w, ban, http.StatusCreated)
+}
+
+// LiftBan ends the ban or suspension named in the URL early
+func (h *BanHandler) LiftBan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	admin := middleware.GetCurrentUser(r)
+	banID := r.PathValue("id")
+	if err := h.BanRepo.Lift(banID, admin.ID); err != nil {
+		if err == repository.ErrBanNotFound {
+			utils.ErrorResponse(w, "No active ban or suspension with this ID", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to lift ban", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditBanLifted, TargetType: models.AuditTargetBan, TargetID: banID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
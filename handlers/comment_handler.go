@@ -2,22 +2,95 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
+	"forum/config"
 	"forum/middleware"
 	"forum/models"
 	"forum/repository"
 	"forum/utils"
 )
 
+// maxCommentLength is the limit of the comments table
+const maxCommentLength = 1000
+
 // CommentHandler handles comment related endpoints
 type CommentHandler struct {
-	CommentRepo *repository.CommentRepository
+	CommentRepo  *repository.CommentRepository
+	ReactionRepo *repository.ReactionRepository
+	PostRepo     *repository.PostRepository
+	CategoryRepo *repository.CategoryRepository
+	Audit        *AuditLogger
 }
 
 // NewCommentHandler creates a new CommentHandler
-func NewCommentHandler(repo *repository.CommentRepository) *CommentHandler {
-	return &CommentHandler{CommentRepo: repo}
+func NewCommentHandler(
+	repo *repository.CommentRepository,
+	reactionRepo *repository.ReactionRepository,
+	postRepo *repository.PostRepository,
+	categoryRepo *repository.CategoryRepository,
+	audit *AuditLogger,
+) *CommentHandler {
+	return &CommentHandler{CommentRepo: repo, ReactionRepo: reactionRepo, PostRepo: postRepo, CategoryRepo: categoryRepo, Audit: audit}
+}
+
+// CommentListResponse is returned by the comment listing endpoint
+type CommentListResponse struct {
+	Comments   []CommentResponse `json:"comments"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// Comments dispatches requests on /forum/api/comments by method
+func (h *CommentHandler) Comments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.ListComments(w, r)
+	case http.MethodPost:
+		h.CreateComment(w, r)
+	default:
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ListComments returns one page of a post's top-level comments (?post_id=&limit=&cursor=),
+// oldest first, each with its replies nested below it
+func (h *CommentHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	postID := r.URL.Query().Get("post_id")
+	if postID == "" {
+		utils.ErrorResponse(w, "post_id is required", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	includeDeleted := seesDeleted(r)
+	comments, next, err := h.CommentRepo.GetCommentsByPostWithUser(postID, page, includeDeleted)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load comments", http.StatusInternalServerError)
+		return
+	}
+
+	builder := threadBuilder{commentRepo: h.CommentRepo, reactionRepo: h.ReactionRepo, includeDeleted: includeDeleted}
+	built, err := builder.buildComments(comments)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load reactions", http.StatusInternalServerError)
+		return
+	}
+
+	response := CommentListResponse{Comments: built, NextCursor: next.Encode()}
+	utils.JSONResponse(w, response, http.StatusOK)
 }
 
 // CreateComment creates a new comment on a post for the authenticated user
@@ -34,17 +107,38 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		PostID  string `json:"post_id"`
-		Content string `json:"content"`
+		PostID   string  `json:"post_id"`
+		ParentID *string `json:"parent_comment_id"` // optional, to reply to another comment
+		Content  string  `json:"content"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	req.Content = strings.TrimSpace(req.Content)
 	if req.PostID == "" || req.Content == "" {
 		utils.ErrorResponse(w, "Post ID and content are required", http.StatusBadRequest)
 		return
 	}
+	if utf8.RuneCountInString(req.Content) > maxCommentLength {
+		utils.ErrorResponse(w, fmt.Sprintf("Content cannot be longer than %d characters", maxCommentLength), http.StatusBadRequest)
+		return
+	}
+
+	// Deleted and hidden posts are closed to new comments
+	post, err := h.PostRepo.GetByID(req.PostID)
+	if err != nil {
+		if err == repository.ErrPostNotFound {
+			utils.ErrorResponse(w, "Post not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to load post", http.StatusInternalServerError)
+		}
+		return
+	}
+	if post.Hidden {
+		utils.ErrorResponse(w, "Post not found", http.StatusNotFound)
+		return
+	}
 
 	comment := models.Comment{
 		PostID:  req.PostID,
@@ -52,6 +146,33 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 		Content: req.Content,
 	}
 
+	if req.ParentID != nil && *req.ParentID != "" {
+		parent, err := h.CommentRepo.GetByID(*req.ParentID)
+		if err != nil {
+			if err == repository.ErrCommentNotFound {
+				utils.ErrorResponse(w, "Parent comment not found", http.StatusBadRequest)
+			} else {
+				utils.ErrorResponse(w, "Failed to load parent comment", http.StatusInternalServerError)
+			}
+			return
+		}
+		// Hidden comments are closed to replies, like hidden posts to comments
+		if parent.Hidden {
+			utils.ErrorResponse(w, "Parent comment not found", http.StatusBadRequest)
+			return
+		}
+		if parent.PostID != req.PostID {
+			utils.ErrorResponse(w, "Parent comment belongs to a different post", http.StatusBadRequest)
+			return
+		}
+		if parent.Depth+1 > config.MaxCommentDepth {
+			utils.ErrorResponse(w, fmt.Sprintf("Replies cannot be nested more than %d levels deep", config.MaxCommentDepth), http.StatusBadRequest)
+			return
+		}
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+
 	created, err := h.CommentRepo.Create(comment)
 	if err != nil {
 		utils.ErrorResponse(w, "Failed to create comment", http.StatusInternalServerError)
@@ -60,3 +181,111 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 
 	utils.JSONResponse(w, created, http.StatusCreated)
 }
+
+// CommentByID dispatches requests on /forum/api/comments/{id} by method
+func (h *CommentHandler) CommentByID(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut, http.MethodPatch:
+		h.UpdateComment(w, r)
+	case http.MethodDelete:
+		h.DeleteComment(w, r)
+	default:
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// loadModifiableComment fetches the comment named in the URL and makes sure the current
+// user wrote it or moderates one of its post's categories. It writes the error response
+// itself and returns nil when the request should stop.
+func (h *CommentHandler) loadModifiableComment(w http.ResponseWriter, r *http.Request) *models.Comment {
+	user := middleware.GetCurrentUser(r)
+	if user == nil {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	comment, err := h.CommentRepo.GetByID(r.PathValue("id"))
+	if err != nil {
+		if err == repository.ErrCommentNotFound {
+			utils.ErrorResponse(w, "Comment not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to load comment", http.StatusInternalServerError)
+		}
+		return nil
+	}
+
+	if comment.UserID == user.ID {
+		return comment
+	}
+
+	categoryIDs, err := h.PostRepo.GetCategoryIDs(comment.PostID)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load comment", http.StatusInternalServerError)
+		return nil
+	}
+	allowed, err := canModerate(h.CategoryRepo, user, categoryIDs)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to check permissions", http.StatusInternalServerError)
+		return nil
+	}
+	if !allowed {
+		utils.ErrorResponse(w, "You can only modify your own comments", http.StatusForbidden)
+		return nil
+	}
+	return comment
+}
+
+// UpdateComment edits the content of a comment owned or moderated by the authenticated user
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	comment := h.loadModifiableComment(w, r)
+	if comment == nil {
+		return
+	}
+
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	comment.Content = strings.TrimSpace(req.Content)
+	if comment.Content == "" {
+		utils.ErrorResponse(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(comment.Content) > maxCommentLength {
+		utils.ErrorResponse(w, fmt.Sprintf("Content cannot be longer than %d characters", maxCommentLength), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.CommentRepo.Update(*comment)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+	if editor := middleware.GetCurrentUser(r); editor.ID != comment.UserID {
+		h.Audit.Record(r, models.AuditEvent{Event: models.AuditCommentModeratorEdit, TargetType: models.TargetComment, TargetID: comment.ID,
+			Details: "author_id=" + comment.UserID})
+	}
+
+	utils.JSONResponse(w, updated, http.StatusOK)
+}
+
+// DeleteComment moves a comment owned or moderated by the authenticated user to the trash;
+// while it has replies, readers see a "[deleted]" placeholder in its place
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	comment := h.loadModifiableComment(w, r)
+	if comment == nil {
+		return
+	}
+
+	if err := h.CommentRepo.Delete(comment.ID, middleware.GetCurrentUser(r).ID); err != nil {
+		utils.ErrorResponse(w, "Failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditDelete, TargetType: models.TargetComment, TargetID: comment.ID,
+		Details: "author_id=" + comment.UserID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
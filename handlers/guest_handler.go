@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"forum/models"
 	"forum/repository"
 	"forum/utils"
 	"net/http"
@@ -23,11 +24,16 @@ type ReactionResponse struct {
 
 type CommentResponse struct {
 	ID        string             `json:"id"`
+	ParentID  *string            `json:"parent_comment_id,omitempty"`
+	Depth     int                `json:"depth"`
 	UserID    string             `json:"user_id"`
 	Username  string             `json:"username"`
 	Content   string             `json:"content"`
 	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt *time.Time         `json:"updated_at,omitempty"` // set once the comment has been edited
+	Deleted   bool               `json:"deleted,omitempty"`    // content replaced by a placeholder
 	Reactions []ReactionResponse `json:"reactions,omitempty"`
+	Replies   []CommentResponse  `json:"replies,omitempty"`
 }
 
 type PostResponse struct {
@@ -36,23 +42,43 @@ type PostResponse struct {
 	Username     string             `json:"username"`
 	CategoryID   int                `json:"category_id"`
 	CategoryName string             `json:"category_name"` // NEW FIELD
+	Categories   []models.Category  `json:"categories"`    // every category the post is listed under
 	Title        string             `json:"title"`         // Optional title field
 	Content      string             `json:"content"`
 	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    *time.Time         `json:"updated_at,omitempty"` // set once the post has been edited
+	Deleted      bool               `json:"deleted,omitempty"`    // a "[removed]" placeholder, moderators only
 	Comments     []CommentResponse  `json:"comments,omitempty"`
 	Reactions    []ReactionResponse `json:"reactions,omitempty"`
+
+	CommentsNextCursor string `json:"comments_next_cursor,omitempty"` // set when more comments exist
 }
 
 type CategoryResponse struct {
-	ID    int            `json:"id"`
-	Name  string         `json:"name"`
-	Posts []PostResponse `json:"posts"`
+	ID         int            `json:"id"`
+	Name       string         `json:"name"`
+	Posts      []PostResponse `json:"posts"`
+	NextCursor string         `json:"next_cursor,omitempty"` // set when the category has more posts
 }
 
 type GuestResponse struct {
 	Categories []CategoryResponse `json:"categories"`
 }
 
+// reactionResponses converts repository reactions to their JSON shape, never returning nil
+func reactionResponses(reactions []models.ReactionWithUser) []ReactionResponse {
+	responses := make([]ReactionResponse, 0, len(reactions))
+	for _, reaction := range reactions {
+		responses = append(responses, ReactionResponse{
+			UserID:       reaction.UserID,
+			Username:     reaction.Username,
+			ReactionType: reaction.ReactionType,
+			CreatedAt:    reaction.CreatedAt,
+		})
+	}
+	return responses
+}
+
 func NewGuestHandler(
 	categoryRepo *repository.CategoryRepository,
 	postRepo *repository.PostRepository,
@@ -67,131 +93,77 @@ func NewGuestHandler(
 	}
 }
 
-func (h *GuestHandler) GuestView(w http.ResponseWriter, r *http.Request) {
+func (h *GuestHandler) GetGuestData(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		//http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		utils.ErrorResponse(w, "Only POST requests are allowed for registration.", http.StatusMethodNotAllowed)
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	posts, err := h.postRepo.GetAllPosts()
+	// limit caps the posts returned per category; further pages come from
+	// /forum/api/posts?category_id=...&cursor=<next_cursor>
+	page, err := parsePage(r)
 	if err != nil {
-		utils.ErrorResponse(w, "Failed to fetch posts.", http.StatusInternalServerError)
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	comments, err := h.commentRepo.GetAllComments()
+	categories, err := h.categoryRepo.GetAll()
 	if err != nil {
-		utils.ErrorResponse(w, "Failed to fetch comments.", http.StatusInternalServerError)
+		utils.ErrorResponse(w, "Failed to load categories", http.StatusInternalServerError)
 		return
 	}
 
-	reactions, err := h.reactionRepo.GetAllReactions()
+	categoryNames := make(map[int]string, len(categories))
+	for _, cat := range categories {
+		categoryNames[cat.ID] = cat.Name
+	}
+
+	// Every category's first page in one query, then comments and reactions in bulk
+	postsByCategory, nextByCategory, err := h.postRepo.GetFirstPagePerCategory(page.Limit)
 	if err != nil {
-		utils.ErrorResponse(w, "Failed to fetch reactions.", http.StatusInternalServerError)
+		utils.ErrorResponse(w, "Failed to load posts", http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]interface{}{
-		"posts":     posts,
-		"comments":  comments,
-		"reactions": reactions,
+	// A post listed under several categories is only built once
+	var unique []models.PostWithUser
+	seen := make(map[string]bool)
+	for _, cat := range categories {
+		for _, post := range postsByCategory[cat.ID] {
+			if !seen[post.ID] {
+				seen[post.ID] = true
+				unique = append(unique, post)
+			}
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	utils.JSONResponse(w, response, http.StatusOK)
-}
-
-func (h *GuestHandler) GetGuestData(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+	builder := threadBuilder{
+		postRepo:      h.postRepo,
+		commentRepo:   h.commentRepo,
+		reactionRepo:  h.reactionRepo,
+		categoryNames: categoryNames,
 	}
-
-	categories, err := h.categoryRepo.GetAll()
+	built, err := builder.buildPosts(unique, true)
 	if err != nil {
-		utils.ErrorResponse(w, "Failed to load categories", http.StatusInternalServerError)
+		utils.ErrorResponse(w, "Failed to load posts", http.StatusInternalServerError)
 		return
 	}
+	postResponses := make(map[string]PostResponse, len(built))
+	for _, postResp := range built {
+		postResponses[postResp.ID] = postResp
+	}
 
 	var response GuestResponse
 	for _, cat := range categories {
 		catResp := CategoryResponse{
-			ID:    cat.ID,
-			Name:  cat.Name,
-			Posts: []PostResponse{}, // ✅ always initialized to avoid null
+			ID:         cat.ID,
+			Name:       cat.Name,
+			Posts:      []PostResponse{}, // ✅ always initialized to avoid null
+			NextCursor: nextByCategory[cat.ID].Encode(),
 		}
-
-		posts, err := h.postRepo.GetPostsByCategoryWithUser(cat.ID)
-		if err != nil {
-			utils.ErrorResponse(w, "Failed to load posts", http.StatusInternalServerError)
-			return
+		for _, post := range postsByCategory[cat.ID] {
+			catResp.Posts = append(catResp.Posts, postResponses[post.ID])
 		}
-
-		for _, post := range posts {
-			postResp := PostResponse{
-				ID:           post.ID,
-				UserID:       post.UserID,
-				Username:     post.Username,
-				CategoryID:   post.CategoryID,
-				CategoryName: cat.Name,   // ✅ inject category name
-				Title:        post.Title, // Optional title field
-				Content:      post.Content,
-				CreatedAt:    post.CreatedAt,
-				Comments:     []CommentResponse{},  // ✅ avoid null
-				Reactions:    []ReactionResponse{}, // ✅ avoid null
-			}
-
-			comments, err := h.commentRepo.GetCommentsByPostWithUser(post.ID)
-			if err != nil {
-				utils.ErrorResponse(w, "Failed to load comments", http.StatusInternalServerError)
-				return
-			}
-
-			for _, comment := range comments {
-				commentResp := CommentResponse{
-					ID:        comment.ID,
-					UserID:    comment.UserID,
-					Username:  comment.Username,
-					Content:   comment.Content,
-					CreatedAt: comment.CreatedAt,
-					Reactions: []ReactionResponse{}, // ✅ avoid null
-				}
-
-				reactions, err := h.reactionRepo.GetReactionsByCommentWithUser(comment.ID)
-				if err != nil {
-					utils.ErrorResponse(w, "Failed to load reactions", http.StatusInternalServerError)
-					return
-				}
-				for _, reaction := range reactions {
-					commentResp.Reactions = append(commentResp.Reactions, ReactionResponse{
-						UserID:       reaction.UserID,
-						Username:     reaction.Username,
-						ReactionType: reaction.ReactionType,
-						CreatedAt:    reaction.CreatedAt,
-					})
-				}
-
-				postResp.Comments = append(postResp.Comments, commentResp)
-			}
-
-			reactions, err := h.reactionRepo.GetReactionsByPostWithUser(post.ID)
-			if err != nil {
-				utils.ErrorResponse(w, "Failed to load reactions", http.StatusInternalServerError)
-				return
-			}
-			for _, reaction := range reactions {
-				postResp.Reactions = append(postResp.Reactions, ReactionResponse{
-					UserID:       reaction.UserID,
-					Username:     reaction.Username,
-					ReactionType: reaction.ReactionType,
-					CreatedAt:    reaction.CreatedAt,
-				})
-			}
-
-			catResp.Posts = append(catResp.Posts, postResp)
-		}
-
 		response.Categories = append(response.Categories, catResp)
 	}
 
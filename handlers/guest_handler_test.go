@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"forum/models"
+	"forum/repository"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// queryCount counts every statement sent through the "sqlite3_counting" driver
+var queryCount atomic.Int64
+
+var registerCountingDriver sync.Once
+
+// countingDriver wraps the SQLite driver and counts the statements its connections run
+type countingDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *countingDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type countingConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryCount.Add(1)
+	return c.SQLiteConn.QueryContext(ctx, query, args)
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	queryCount.Add(1)
+	return c.SQLiteConn.ExecContext(ctx, query, args)
+}
+
+func (c *countingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	queryCount.Add(1)
+	return c.SQLiteConn.PrepareContext(ctx, query)
+}
+
+// seedGuestData creates a database with postsPerCategory posts in every
+// category, each with comments, replies and reactions, and opens it through
+// the counting driver
+func seedGuestData(tb testing.TB, postsPerCategory int) *sql.DB {
+	tb.Helper()
+	registerCountingDriver.Do(func() {
+		sql.Register("sqlite3_counting", &countingDriver{})
+	})
+
+	// InitDB creates ./database/forum.db with the full schema
+	dir := tb.TempDir()
+	tb.Chdir(dir)
+	setup, err := models.InitDB()
+	if err != nil {
+		tb.Fatalf("init database: %v", err)
+	}
+	defer setup.Close()
+
+	var categories []int
+	rows, err := setup.Query(`SELECT category_id FROM categories`)
+	if err != nil {
+		tb.Fatalf("load categories: %v", err)
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			tb.Fatalf("scan category: %v", err)
+		}
+		categories = append(categories, id)
+	}
+	rows.Close()
+
+	tx, err := setup.Begin()
+	if err != nil {
+		tb.Fatalf("begin: %v", err)
+	}
+	exec := func(query string, args ...interface{}) {
+		if _, err := tx.Exec(query, args...); err != nil {
+			tb.Fatalf("seed: %v", err)
+		}
+	}
+	exec(`INSERT INTO user (user_id, username, email) VALUES ('bench-user', 'bench', 'bench@example.com')`)
+	for i := 0; i < postsPerCategory*len(categories); i++ {
+		postID := fmt.Sprintf("bench-post-%d", i)
+		category := categories[i%len(categories)]
+		exec(`INSERT INTO posts (post_id, user_id, category_id, title, content) VALUES (?, 'bench-user', ?, 'Title', 'Content')`,
+			postID, category)
+		exec(`INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)`, postID, category)
+		exec(`INSERT INTO reactions (user_id, reaction_type, post_id) VALUES ('bench-user', 1, ?)`, postID)
+		for j := 0; j < 3; j++ {
+			commentID := fmt.Sprintf("%s-comment-%d", postID, j)
+			replyID := commentID + "-reply"
+			exec(`INSERT INTO comments (comment_id, post_id, user_id, content) VALUES (?, ?, 'bench-user', 'Comment')`,
+				commentID, postID)
+			exec(`INSERT INTO comments (comment_id, post_id, user_id, content, parent_comment_id, depth)
+				VALUES (?, ?, 'bench-user', 'Reply', ?, 1)`, replyID, postID, commentID)
+			exec(`INSERT INTO reactions (user_id, reaction_type, comment_id) VALUES ('bench-user', 2, ?)`, commentID)
+			exec(`INSERT INTO reactions (user_id, reaction_type, comment_id) VALUES ('bench-user', 3, ?)`, replyID)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tb.Fatalf("commit: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3_counting", filepath.Join(dir, "database", "forum.db")+"?_foreign_keys=on")
+	if err != nil {
+		tb.Fatalf("open database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestGuestHandler wires a GuestHandler to the database
+func newTestGuestHandler(db *sql.DB) *GuestHandler {
+	return NewGuestHandler(
+		repository.NewCategoryRepository(db),
+		repository.NewPostRepository(db),
+		repository.NewCommentRepository(db),
+		repository.NewReactionRepository(db),
+	)
+}
+
+// guestDataQueries serves one guest page and returns the number of statements it ran
+func guestDataQueries(tb testing.TB, h *GuestHandler) int64 {
+	tb.Helper()
+	rec := httptest.NewRecorder()
+	before := queryCount.Load()
+	h.GetGuestData(rec, httptest.NewRequest(http.MethodGet, "/forum/api/guest", nil))
+	if rec.Code != http.StatusOK {
+		tb.Fatalf("GetGuestData returned %d: %s", rec.Code, rec.Body)
+	}
+	return queryCount.Load() - before
+}
+
+// Once every category fills its first page, more data must not mean more queries
+func TestGetGuestDataQueryCountIsConstant(t *testing.T) {
+	small := guestDataQueries(t, newTestGuestHandler(seedGuestData(t, defaultPageSize)))
+	large := guestDataQueries(t, newTestGuestHandler(seedGuestData(t, 10*defaultPageSize)))
+	if small != large {
+		t.Errorf("GetGuestData ran %d queries for %d posts per category but %d for %d",
+			small, defaultPageSize, large, 10*defaultPageSize)
+	}
+}
+
+func BenchmarkGetGuestData(b *testing.B) {
+	var counts []int64
+	sizes := []int{defaultPageSize, 10 * defaultPageSize}
+	for _, posts := range sizes {
+		b.Run(fmt.Sprintf("posts_per_category=%d", posts), func(b *testing.B) {
+			h := newTestGuestHandler(seedGuestData(b, posts))
+			var queries int64
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				queries = guestDataQueries(b, h)
+			}
+			b.ReportMetric(float64(queries), "queries/op")
+			counts = append(counts, queries)
+		})
+	}
+	if len(counts) == 2 && counts[0] != counts[1] {
+		b.Errorf("GetGuestData ran %d queries for %d posts per category but %d for %d",
+			counts[0], sizes[0], counts[1], sizes[1])
+	}
+}
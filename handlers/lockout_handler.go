@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+// LockoutHandler lets administrators review login lockouts
+type LockoutHandler struct {
+	LockoutRepo *repository.LockoutRepository
+}
+
+// NewLockoutHandler creates a new LockoutHandler
+func NewLockoutHandler(lockoutRepo *repository.LockoutRepository) *LockoutHandler {
+	return &LockoutHandler{LockoutRepo: lockoutRepo}
+}
+
+// LockoutListResponse is one page of the lockout history
+type LockoutListResponse struct {
+	Lockouts   []models.LoginLockout `json:"lockouts"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// ListLockouts lists login lockouts, newest first, filtered by ?scope=
+// (account or ip) and ?identifier= (the account email or IP address)
+func (h *LockoutHandler) ListLockouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.LockoutFilter{Scope: query.Get("scope"), Identifier: query.Get("identifier")}
+	if filter.Scope != "" && filter.Scope != models.LockoutScopeAccount && filter.Scope != models.LockoutScopeIP {
+		utils.ErrorResponse(w, "Scope must be account or ip", http.StatusBadRequest)
+		return
+	}
+
+	lockouts, next, err := h.LockoutRepo.List(filter, page)
+	if err != nil {
+		if err == models.ErrInvalidCursor {
+			utils.ErrorResponse(w, "Invalid cursor", http.StatusBadRequest)
+		} else {
+			utils.ErrorResponse(w, "Failed to load lockouts", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.JSONResponse(w, LockoutListResponse{Lockouts: lockouts, NextCursor: next.Encode()}, http.StatusOK)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"forum/models"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parsePage reads the limit and cursor query parameters shared by every list endpoint
+func parsePage(r *http.Request) (models.Page, error) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		return models.Page{}, err
+	}
+	page := models.Page{Limit: limit}
+
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		cursor, err := models.DecodeCursor(raw)
+		if err != nil {
+			return page, errors.New("Invalid cursor")
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
+
+// parseLimit reads the limit query parameter, clamped to maxPageSize
+func parseLimit(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("Limit must be a positive number")
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return limit, nil
+}
+
+// Ranked listings such as search results page by offset rather than by keyset;
+// the offset is still handed out as an opaque cursor.
+
+func encodeOffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeOffsetCursor(token string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errors.New("Invalid cursor")
+	}
+	offset, err := strconv.Atoi(string(data))
+	if err != nil || offset < 0 {
+		return 0, errors.New("Invalid cursor")
+	}
+	return offset, nil
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/repository"
+)
+
+// seesDeleted reports whether the request comes from a moderator or
+// administrator, who see deleted posts and comments as placeholders
+func seesDeleted(r *http.Request) bool {
+	user := middleware.GetCurrentUser(r)
+	return user != nil && user.HasRole(models.RoleModerator)
+}
+
+// canModerate reports whether the user may edit or delete other users' content
+// listed under the given categories: administrators everywhere, moderators in
+// the categories assigned to them
+func canModerate(categoryRepo *repository.CategoryRepository, user *models.User, categoryIDs []int) (bool, error) {
+	if user.HasRole(models.RoleAdmin) {
+		return true, nil
+	}
+	if !user.HasRole(models.RoleModerator) {
+		return false, nil
+	}
+	return categoryRepo.IsModerator(user.ID, categoryIDs)
+}
@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"forum/middleware"
 	"forum/models"
@@ -10,14 +14,131 @@ import (
 	"forum/utils"
 )
 
+// Limits of the posts table
+const (
+	maxPostTitleLength   = 200
+	maxPostContentLength = 2000
+)
+
 // PostHandler handles post related endpoints
 type PostHandler struct {
-	PostRepo *repository.PostRepository
+	PostRepo     *repository.PostRepository
+	CategoryRepo *repository.CategoryRepository
+	CommentRepo  *repository.CommentRepository
+	ReactionRepo *repository.ReactionRepository
+	Audit        *AuditLogger
 }
 
 // NewPostHandler creates a new PostHandler
-func NewPostHandler(repo *repository.PostRepository) *PostHandler {
-	return &PostHandler{PostRepo: repo}
+func NewPostHandler(
+	repo *repository.PostRepository,
+	categoryRepo *repository.CategoryRepository,
+	commentRepo *repository.CommentRepository,
+	reactionRepo *repository.ReactionRepository,
+	audit *AuditLogger,
+) *PostHandler {
+	return &PostHandler{
+		PostRepo:     repo,
+		CategoryRepo: categoryRepo,
+		CommentRepo:  commentRepo,
+		ReactionRepo: reactionRepo,
+		Audit:        audit,
+	}
+}
+
+// PostListResponse is returned by the post listing endpoint
+type PostListResponse struct {
+	Posts      []PostResponse `json:"posts"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// Posts dispatches requests on /forum/api/posts by method
+func (h *PostHandler) Posts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.ListPosts(w, r)
+	case http.MethodPost:
+		h.CreatePost(w, r)
+	default:
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ListPosts lists posts page by page (?limit=&cursor=), optionally filtered by
+// category (?category_id=), by the current user's own posts (?mine=true) or by
+// posts the current user liked or loved (?liked=true). The last two require
+// authentication.
+func (h *PostHandler) ListPosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	var filter models.PostFilter
+
+	if raw := query.Get("category_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(w, "Invalid category_id", http.StatusBadRequest)
+			return
+		}
+		filter.CategoryID = id
+	}
+
+	mine := query.Get("mine") == "true"
+	liked := query.Get("liked") == "true"
+	if mine || liked {
+		user := middleware.GetCurrentUser(r)
+		if user == nil {
+			utils.ErrorResponse(w, "Login required for the mine and liked filters", http.StatusUnauthorized)
+			return
+		}
+		if mine {
+			filter.AuthorID = user.ID
+		}
+		if liked {
+			filter.LikedBy = user.ID
+		}
+	}
+
+	categories, err := h.CategoryRepo.GetAll()
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load categories", http.StatusInternalServerError)
+		return
+	}
+	categoryNames := make(map[int]string, len(categories))
+	for _, cat := range categories {
+		categoryNames[cat.ID] = cat.Name
+	}
+
+	filter.IncludeDeleted = seesDeleted(r)
+	posts, next, err := h.PostRepo.ListPosts(filter, page)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	builder := threadBuilder{
+		postRepo:       h.PostRepo,
+		reactionRepo:   h.ReactionRepo,
+		categoryNames:  categoryNames,
+		includeDeleted: filter.IncludeDeleted,
+	}
+	built, err := builder.buildPosts(posts, false)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	response := PostListResponse{Posts: built, NextCursor: next.Encode()}
+	utils.JSONResponse(w, response, http.StatusOK)
 }
 
 // CreatePost creates a new post for the authenticated user
@@ -34,24 +155,44 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		CategoryID int    `json:"category_id"`
-		Title      string `json:"title"`
-		Content    string `json:"content"`
+		CategoryID  int    `json:"category_id"` // kept for clients that only send one category
+		CategoryIDs []int  `json:"category_ids"`
+		Title       string `json:"title"`
+		Content     string `json:"content"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	if req.CategoryID == 0 || req.Title == "" || req.Content == "" {
+	if len(req.CategoryIDs) == 0 && req.CategoryID != 0 {
+		req.CategoryIDs = []int{req.CategoryID}
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	req.Content = strings.TrimSpace(req.Content)
+	if len(req.CategoryIDs) == 0 || req.Title == "" || req.Content == "" {
 		utils.ErrorResponse(w, "Category, title and content are required", http.StatusBadRequest)
 		return
 	}
+	if msg := validatePost(req.Title, req.Content); msg != "" {
+		utils.ErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	categoryIDs, msg, err := h.validateCategories(req.CategoryIDs)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load categories", http.StatusInternalServerError)
+		return
+	}
+	if msg != "" {
+		utils.ErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
 
 	post := models.Post{
-		UserID:     user.ID,
-		CategoryID: req.CategoryID,
-		Title:      req.Title,
-		Content:    req.Content,
+		UserID:      user.ID,
+		CategoryIDs: categoryIDs,
+		Title:       req.Title,
+		Content:     req.Content,
 	}
 
 	created, err := h.PostRepo.Create(post)
@@ -62,3 +203,257 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 
 	utils.JSONResponse(w, created, http.StatusCreated)
 }
+
+// validatePost checks a title and content against the limits of the posts table.
+// A non-empty message means the request should be rejected with 400.
+func validatePost(title, content string) string {
+	if utf8.RuneCountInString(title) > maxPostTitleLength {
+		return fmt.Sprintf("Title cannot be longer than %d characters", maxPostTitleLength)
+	}
+	if utf8.RuneCountInString(content) > maxPostContentLength {
+		return fmt.Sprintf("Content cannot be longer than %d characters", maxPostContentLength)
+	}
+	return ""
+}
+
+// validateCategories removes duplicate IDs and checks that every category exists.
+// A non-empty message means the request should be rejected with 400.
+func (h *PostHandler) validateCategories(ids []int) ([]int, string, error) {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	found, err := h.CategoryRepo.GetByIDs(unique)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(found) != len(unique) {
+		known := make(map[int]bool, len(found))
+		for _, cat := range found {
+			known[cat.ID] = true
+		}
+		for _, id := range unique {
+			if !known[id] {
+				return nil, fmt.Sprintf("Unknown category ID %d", id), nil
+			}
+		}
+	}
+	return unique, "", nil
+}
+
+// PostDetailResponse is a single post with one page of its comments
+type PostDetailResponse struct {
+	PostResponse
+	ReactionCounts models.ReactionCounts `json:"reaction_counts"`
+	UserReaction   *int                  `json:"user_reaction"` // nil for guests and users who have not reacted
+}
+
+// PostByID dispatches requests on /forum/api/posts/{id} by method
+func (h *PostHandler) PostByID(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetPost(w, r)
+	case http.MethodPut, http.MethodPatch:
+		h.UpdatePost(w, r)
+	case http.MethodDelete:
+		h.DeletePost(w, r)
+	default:
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetPost returns a post with its categories, reactions and one page of
+// comments (?limit=&cursor= page through the comments)
+func (h *PostHandler) GetPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	includeDeleted := seesDeleted(r)
+	post, err := h.PostRepo.GetByIDWithUser(r.PathValue("id"), includeDeleted)
+	if err != nil {
+		if err == repository.ErrPostNotFound {
+			utils.ErrorResponse(w, "Post not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to load post", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	categories, err := h.CategoryRepo.GetAll()
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load categories", http.StatusInternalServerError)
+		return
+	}
+	categoryNames := make(map[int]string, len(categories))
+	for _, cat := range categories {
+		categoryNames[cat.ID] = cat.Name
+	}
+
+	builder := threadBuilder{
+		postRepo:       h.PostRepo,
+		commentRepo:    h.CommentRepo,
+		reactionRepo:   h.ReactionRepo,
+		categoryNames:  categoryNames,
+		includeDeleted: includeDeleted,
+	}
+	built, err := builder.buildPosts([]models.PostWithUser{*post}, false)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load post", http.StatusInternalServerError)
+		return
+	}
+	response := PostDetailResponse{PostResponse: built[0]}
+
+	comments, next, err := h.CommentRepo.GetCommentsByPostWithUser(post.ID, page, includeDeleted)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load comments", http.StatusInternalServerError)
+		return
+	}
+	response.Comments, err = builder.buildComments(comments)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load comments", http.StatusInternalServerError)
+		return
+	}
+	response.CommentsNextCursor = next.Encode()
+
+	user := middleware.GetCurrentUser(r)
+	for _, reaction := range response.Reactions {
+		switch reaction.ReactionType {
+		case models.ReactionLike:
+			response.ReactionCounts.Like++
+		case models.ReactionDislike:
+			response.ReactionCounts.Dislike++
+		case models.ReactionLove:
+			response.ReactionCounts.Love++
+		}
+		if user != nil && reaction.UserID == user.ID {
+			reactionType := reaction.ReactionType
+			response.UserReaction = &reactionType
+		}
+	}
+
+	utils.JSONResponse(w, response, http.StatusOK)
+}
+
+// loadModifiablePost fetches the post named in the URL and makes sure the current user
+// wrote it or moderates one of its categories. It writes the error response itself
+// and returns nil when the request should stop.
+func (h *PostHandler) loadModifiablePost(w http.ResponseWriter, r *http.Request) *models.Post {
+	user := middleware.GetCurrentUser(r)
+	if user == nil {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	post, err := h.PostRepo.GetByID(r.PathValue("id"))
+	if err != nil {
+		if err == repository.ErrPostNotFound {
+			utils.ErrorResponse(w, "Post not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to load post", http.StatusInternalServerError)
+		}
+		return nil
+	}
+
+	if post.UserID == user.ID {
+		return post
+	}
+
+	categoryIDs, err := h.PostRepo.GetCategoryIDs(post.ID)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load post", http.StatusInternalServerError)
+		return nil
+	}
+	allowed, err := canModerate(h.CategoryRepo, user, categoryIDs)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to check permissions", http.StatusInternalServerError)
+		return nil
+	}
+	if !allowed {
+		utils.ErrorResponse(w, "You can only modify your own posts", http.StatusForbidden)
+		return nil
+	}
+	return post
+}
+
+// UpdatePost edits the title and/or content of a post owned or moderated by the authenticated user.
+// PUT requires both fields, PATCH accepts either.
+func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	post := h.loadModifiablePost(w, r)
+	if post == nil {
+		return
+	}
+
+	var req struct {
+		Title   *string `json:"title"`
+		Content *string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPut && (req.Title == nil || req.Content == nil) {
+		utils.ErrorResponse(w, "Title and content are required", http.StatusBadRequest)
+		return
+	}
+	if req.Title == nil && req.Content == nil {
+		utils.ErrorResponse(w, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	if req.Title != nil {
+		post.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Content != nil {
+		post.Content = strings.TrimSpace(*req.Content)
+	}
+	if post.Title == "" || post.Content == "" {
+		utils.ErrorResponse(w, "Title and content cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if msg := validatePost(post.Title, post.Content); msg != "" {
+		utils.ErrorResponse(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.PostRepo.Update(*post)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to update post", http.StatusInternalServerError)
+		return
+	}
+	if editor := middleware.GetCurrentUser(r); editor.ID != post.UserID {
+		h.Audit.Record(r, models.AuditEvent{Event: models.AuditPostModeratorEdit, TargetType: models.TargetPost, TargetID: post.ID,
+			Details: "author_id=" + post.UserID})
+	}
+
+	utils.JSONResponse(w, updated, http.StatusOK)
+}
+
+// DeletePost moves a post owned or moderated by the authenticated user to the trash
+func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
+	post := h.loadModifiablePost(w, r)
+	if post == nil {
+		return
+	}
+
+	if err := h.PostRepo.Delete(post.ID, middleware.GetCurrentUser(r).ID); err != nil {
+		utils.ErrorResponse(w, "Failed to delete post", http.StatusInternalServerError)
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditDelete, TargetType: models.TargetPost, TargetID: post.ID,
+		Details: "author_id=" + post.UserID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
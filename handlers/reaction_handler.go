@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+// ReactionHandler handles reaction related endpoints
+type ReactionHandler struct {
+	ReactionRepo *repository.ReactionRepository
+	PostRepo     *repository.PostRepository
+	CommentRepo  *repository.CommentRepository
+}
+
+// NewReactionHandler creates a new ReactionHandler
+func NewReactionHandler(reactionRepo *repository.ReactionRepository, postRepo *repository.PostRepository, commentRepo *repository.CommentRepository) *ReactionHandler {
+	return &ReactionHandler{
+		ReactionRepo: reactionRepo,
+		PostRepo:     postRepo,
+		CommentRepo:  commentRepo,
+	}
+}
+
+// ReactResponse is returned after a reaction has been toggled
+type ReactResponse struct {
+	TargetID     string                `json:"target_id"`
+	TargetType   string                `json:"target_type"`
+	ReactionType *int                  `json:"reaction_type"` // nil when the reaction was removed
+	Counts       models.ReactionCounts `json:"counts"`
+}
+
+// React creates, switches or removes the authenticated user's reaction on a post or comment
+func (h *ReactionHandler) React(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetCurrentUser(r)
+	if user == nil {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TargetID     string `json:"target_id"`
+		TargetType   string `json:"target_type"`
+		ReactionType int    `json:"reaction_type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TargetID == "" {
+		utils.ErrorResponse(w, "Target ID is required", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidReactionType(req.ReactionType) {
+		utils.ErrorResponse(w, "Reaction type must be 1 (like), 2 (dislike) or 3 (love)", http.StatusBadRequest)
+		return
+	}
+
+	if req.TargetType != models.TargetPost && req.TargetType != models.TargetComment {
+		utils.ErrorResponse(w, "Target type must be \"post\" or \"comment\"", http.StatusBadRequest)
+		return
+	}
+
+	// Make sure the target exists before touching the reactions table
+	if err := h.checkVisible(req.TargetType, req.TargetID); err != nil {
+		if err == repository.ErrPostNotFound || err == repository.ErrCommentNotFound {
+			utils.ErrorResponse(w, "Target not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to load target", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	current, err := h.ReactionRepo.Toggle(user.ID, req.TargetType, req.TargetID, req.ReactionType)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to save reaction", http.StatusInternalServerError)
+		return
+	}
+
+	counts, err := h.ReactionRepo.GetCounts(req.TargetType, req.TargetID)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load reactions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, ReactResponse{
+		TargetID:     req.TargetID,
+		TargetType:   req.TargetType,
+		ReactionType: current,
+		Counts:       counts,
+	}, http.StatusOK)
+}
+
+// checkVisible returns ErrPostNotFound or ErrCommentNotFound unless the target
+// and the post it belongs to are neither deleted nor hidden. Everyone else sees
+// such content as gone, so it cannot be reacted to either.
+func (h *ReactionHandler) checkVisible(targetType, targetID string) error {
+	postID := targetID
+	if targetType == models.TargetComment {
+		comment, err := h.CommentRepo.GetByID(targetID)
+		if err != nil {
+			return err
+		}
+		if comment.Hidden {
+			return repository.ErrCommentNotFound
+		}
+		postID = comment.PostID
+	}
+
+	post, err := h.PostRepo.GetByID(postID)
+	if err != nil {
+		return err
+	}
+	if post.Hidden {
+		return repository.ErrPostNotFound
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"forum/mailer"
+	"forum/middleware"
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+const maxReportReasonLength = 500
+
+// ReportHandler lets users report posts and comments and moderators work through the reports
+type ReportHandler struct {
+	ReportRepo   *repository.ReportRepository
+	PostRepo     *repository.PostRepository
+	CommentRepo  *repository.CommentRepository
+	CategoryRepo *repository.CategoryRepository
+	UserRepo     *repository.UserRepository
+	Mailer       mailer.Mailer // warns authors
+	Audit        *AuditLogger
+}
+
+// NewReportHandler creates a new ReportHandler
+func NewReportHandler(
+	reportRepo *repository.ReportRepository,
+	postRepo *repository.PostRepository,
+	commentRepo *repository.CommentRepository,
+	categoryRepo *repository.CategoryRepository,
+	userRepo *repository.UserRepository,
+	m mailer.Mailer,
+	audit *AuditLogger,
+) *ReportHandler {
+	return &ReportHandler{
+		ReportRepo:   reportRepo,
+		PostRepo:     postRepo,
+		CommentRepo:  commentRepo,
+		CategoryRepo: categoryRepo,
+		UserRepo:     userRepo,
+		Mailer:       m,
+		Audit:        audit,
+	}
+}
+
+// ReportQueueResponse is one page of the moderation queue
+type ReportQueueResponse struct {
+	Targets    []models.ReportedTarget `json:"targets"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+// ModerationHistoryResponse is one page of the moderation history
+type ModerationHistoryResponse struct {
+	Actions    []models.ModerationAction `json:"actions"`
+	NextCursor string                    `json:"next_cursor,omitempty"`
+}
+
+// reportTarget is the author and categories of a reported post or comment
+type reportTarget struct {
+	authorID    string
+	categoryIDs []int
+}
+
+// CreateReport files the authenticated user's report of a post or comment
+func (h *ReportHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetCurrentUser(r)
+	if user == nil {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TargetType string `json:"target_type"`
+		TargetID   string `json:"target_id"`
+		Reason     string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxReportReasonLength {
+		utils.ErrorResponse(w, fmt.Sprintf("A reason of at most %d characters is required", maxReportReasonLength), http.StatusBadRequest)
+		return
+	}
+
+	target, ok := h.loadTarget(w, req.TargetType, req.TargetID)
+	if !ok {
+		return
+	}
+	if target.authorID == user.ID {
+		utils.ErrorResponse(w, "You cannot report your own content", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.ReportRepo.Create(models.Report{
+		ReporterID: user.ID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		if err == repository.ErrAlreadyReported {
+			utils.ErrorResponse(w, "You have already reported this "+req.TargetType, http.StatusConflict)
+		} else {
+			utils.ErrorResponse(w, "Failed to save report", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	utils.JSONResponse(w, report, http.StatusCreated)
+}
+
+// Queue lists reported content with its open reports (?limit=&cursor=), most
+// reported first. Moderators see the categories they moderate, administrators everything.
+func (h *ReportHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := middleware.GetCurrentUser(r)
+	scope := user.ID
+	if user.HasRole(models.RoleAdmin) {
+		scope = ""
+	}
+
+	targets, next, err := h.ReportRepo.ListOpenTargets(scope, page)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load reports", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, ReportQueueResponse{Targets: targets, NextCursor: next.Encode()}, http.StatusOK)
+}
+
+// Resolve closes the open reports on a post or comment by dismissing them,
+// hiding the content or warning its author
+func (h *ReportHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		TargetType string `json:"target_type"`
+		TargetID   string `json:"target_id"`
+		Action     string `json:"action"`
+		Note       string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidModerationAction(req.Action) {
+		utils.ErrorResponse(w, "Action must be dismiss, hide or warn", http.StatusBadRequest)
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(req.Note) > maxReportReasonLength {
+		utils.ErrorResponse(w, fmt.Sprintf("The note can be at most %d characters", maxReportReasonLength), http.StatusBadRequest)
+		return
+	}
+
+	target, ok := h.loadTarget(w, req.TargetType, req.TargetID)
+	if !ok {
+		return
+	}
+
+	moderator := middleware.GetCurrentUser(r)
+	allowed, err := canModerate(h.CategoryRepo, moderator, target.categoryIDs)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		utils.ErrorResponse(w, "You do not moderate this category", http.StatusForbidden)
+		return
+	}
+
+	action, err := h.ReportRepo.Resolve(models.ModerationAction{
+		ModeratorID:  moderator.ID,
+		Action:       req.Action,
+		TargetType:   req.TargetType,
+		TargetID:     req.TargetID,
+		TargetUserID: target.authorID,
+		Note:         req.Note,
+	})
+	if err != nil {
+		if err == repository.ErrNoOpenReports {
+			utils.ErrorResponse(w, "There are no open reports on this "+req.TargetType, http.StatusConflict)
+		} else {
+			utils.ErrorResponse(w, "Failed to resolve reports", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditReportsResolved, TargetType: action.TargetType, TargetID: action.TargetID,
+		Details: "action=" + action.Action + " author_id=" + action.TargetUserID})
+
+	// The decision is recorded either way; a lost warning mail is only logged
+	if action.Action == models.ModerationWarn {
+		if err := h.sendWarning(action); err != nil {
+			slog.Error("Failed to send warning mail", "user_id", action.TargetUserID, "error", err)
+		}
+	}
+
+	utils.JSONResponse(w, action, http.StatusOK)
+}
+
+// History lists past moderation actions, newest first, filtered by
+// ?target_type=&target_id=, ?user_id= (the author), ?moderator_id= and ?action=.
+// Moderators see the categories they moderate, administrators everything.
+func (h *ReportHandler) History(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.ModerationFilter{
+		TargetType:   query.Get("target_type"),
+		TargetID:     query.Get("target_id"),
+		TargetUserID: query.Get("user_id"),
+		ModeratorID:  query.Get("moderator_id"),
+		Action:       query.Get("action"),
+	}
+
+	user := middleware.GetCurrentUser(r)
+	scope := user.ID
+	if user.HasRole(models.RoleAdmin) {
+		scope = ""
+	}
+
+	actions, next, err := h.ReportRepo.ListActions(scope, filter, page)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load moderation history", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, ModerationHistoryResponse{Actions: actions, NextCursor: next.Encode()}, http.StatusOK)
+}
+
+// loadTarget looks up the author and categories of a post or comment.
+// It writes the error response itself and reports false when the request should stop.
+func (h *ReportHandler) loadTarget(w http.ResponseWriter, targetType, targetID string) (reportTarget, bool) {
+	var target reportTarget
+	postID := targetID
+
+	switch targetType {
+	case models.TargetPost:
+		post, err := h.PostRepo.GetByID(targetID)
+		if err != nil {
+			if err == repository.ErrPostNotFound {
+				utils.ErrorResponse(w, "Post not found", http.StatusNotFound)
+			} else {
+				utils.ErrorResponse(w, "Failed to load post", http.StatusInternalServerError)
+			}
+			return target, false
+		}
+		target.authorID = post.UserID
+	case models.TargetComment:
+		comment, err := h.CommentRepo.GetByID(targetID)
+		if err != nil {
+			if err == repository.ErrCommentNotFound {
+				utils.ErrorResponse(w, "Comment not found", http.StatusNotFound)
+			} else {
+				utils.ErrorResponse(w, "Failed to load comment", http.StatusInternalServerError)
+			}
+			return target, false
+		}
+		target.authorID = comment.UserID
+		postID = comment.PostID
+	default:
+		utils.ErrorResponse(w, "Target type must be post or comment", http.StatusBadRequest)
+		return target, false
+	}
+
+	var err error
+	target.categoryIDs, err = h.PostRepo.GetCategoryIDs(postID)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load categories", http.StatusInternalServerError)
+		return target, false
+	}
+	return target, true
+}
+
+// sendWarning mails the author of moderated content the moderator's warning
+func (h *ReportHandler) sendWarning(action *models.ModerationAction) error {
+	author, err := h.UserRepo.GetByID(action.TargetUserID)
+	if err != nil {
+		return err
+	}
+
+	note := action.Note
+	if note == "" {
+		note = "(no details given)"
+	}
+	return h.Mailer.Send(mailer.Message{
+		To:      author.Email,
+		Subject: "A warning from the moderators",
+		Body: fmt.Sprintf("Hello %s,\n\nA moderator reviewed reports about one of your %ss and issued a warning:\n\n%s\n\nPlease keep to the forum rules. Repeated violations can lead to your account being suspended.\n",
+			author.Username, action.TargetType, note),
+	})
+}
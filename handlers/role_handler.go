@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+// RoleHandler lets administrators assign roles and category moderators
+type RoleHandler struct {
+	UserRepo     *repository.UserRepository
+	CategoryRepo *repository.CategoryRepository
+	Audit        *AuditLogger
+}
+
+// NewRoleHandler creates a new RoleHandler
+func NewRoleHandler(userRepo *repository.UserRepository, categoryRepo *repository.CategoryRepository, audit *AuditLogger) *RoleHandler {
+	return &RoleHandler{UserRepo: userRepo, CategoryRepo: categoryRepo, Audit: audit}
+}
+
+// SetUserRole changes the role of the user named in the URL
+func (h *RoleHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		utils.ErrorResponse(w, "Role must be user, moderator or admin", http.StatusBadRequest)
+		return
+	}
+
+	// Keep at least the acting administrator in charge
+	admin := middleware.GetCurrentUser(r)
+	userID := r.PathValue("id")
+	if userID == admin.ID && req.Role != models.RoleAdmin {
+		utils.ErrorResponse(w, "You cannot remove your own administrator role", http.StatusConflict)
+		return
+	}
+
+	if err := h.UserRepo.SetRole(userID, req.Role); err != nil {
+		if err == repository.ErrUserNotFound {
+			utils.ErrorResponse(w, "User not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to update role", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditRoleChange, TargetType: models.AuditTargetUser, TargetID: userID, Details: "role=" + req.Role})
+
+	user, err := h.UserRepo.GetByID(userID)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load user", http.StatusInternalServerError)
+		return
+	}
+	utils.JSONResponse(w, user, http.StatusOK)
+}
+
+// CategoryModerators lists (GET) or assigns (POST) the moderators of the category named in the URL
+func (h *RoleHandler) CategoryModerators(w http.ResponseWriter, r *http.Request) {
+	categoryID, ok := h.loadCategoryID(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		moderators, err := h.CategoryRepo.ListModerators(categoryID)
+		if err != nil {
+			utils.ErrorResponse(w, "Failed to load moderators", http.StatusInternalServerError)
+			return
+		}
+		utils.JSONResponse(w, map[string]interface{}{"moderators": moderators}, http.StatusOK)
+	case http.MethodPost:
+		h.addModerator(w, r, categoryID)
+	default:
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// addModerator assigns a moderator or administrator to the category
+func (h *RoleHandler) addModerator(w http.ResponseWriter, r *http.Request, categoryID int) {
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.UserRepo.GetByID(req.UserID)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			utils.ErrorResponse(w, "User not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to load user", http.StatusInternalServerError)
+		}
+		return
+	}
+	if !user.HasRole(models.RoleModerator) {
+		utils.ErrorResponse(w, "Give the user the moderator role first", http.StatusConflict)
+		return
+	}
+
+	if err := h.CategoryRepo.AddModerator(categoryID, user.ID); err != nil {
+		utils.ErrorResponse(w, "Failed to assign moderator", http.StatusInternalServerError)
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditModeratorAssigned, TargetType: models.AuditTargetCategory,
+		TargetID: strconv.Itoa(categoryID), Details: "user_id=" + user.ID})
+
+	utils.JSONResponse(w, map[string]interface{}{"category_id": categoryID, "user_id": user.ID}, http.StatusCreated)
+}
+
+// RemoveCategoryModerator ends a user's assignment to the category named in the URL
+func (h *RoleHandler) RemoveCategoryModerator(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	categoryID, ok := h.loadCategoryID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.PathValue("user_id")
+	if err := h.CategoryRepo.RemoveModerator(categoryID, userID); err != nil {
+		if err == repository.ErrModeratorNotFound {
+			utils.ErrorResponse(w, "User does not moderate this category", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to remove moderator", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditModeratorRemoved, TargetType: models.AuditTargetCategory,
+		TargetID: strconv.Itoa(categoryID), Details: "user_id=" + userID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadCategoryID parses the category ID in the URL and makes sure the category exists.
+// It writes the error response itself and reports false when the request should stop.
+func (h *RoleHandler) loadCategoryID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.ErrorResponse(w, "Invalid category ID", http.StatusBadRequest)
+		return 0, false
+	}
+	categories, err := h.CategoryRepo.GetByIDs([]int{id})
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load category", http.StatusInternalServerError)
+		return 0, false
+	}
+	if len(categories) == 0 {
+		utils.ErrorResponse(w, "Category not found", http.StatusNotFound)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+// SearchHandler handles full-text search
+type SearchHandler struct {
+	SearchRepo *repository.SearchRepository
+}
+
+// NewSearchHandler creates a new SearchHandler
+func NewSearchHandler(repo *repository.SearchRepository) *SearchHandler {
+	return &SearchHandler{SearchRepo: repo}
+}
+
+// SearchResponse is returned by the search endpoint
+type SearchResponse struct {
+	Results    []models.SearchResult `json:"results"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// Search looks up posts and comments matching ?q=, optionally within
+// ?category_id=, ranked by relevance and paginated with ?limit=&cursor=
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.SearchRepo.Available() {
+		utils.ErrorResponse(w, "Search is not available on this server", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	search := models.SearchQuery{Terms: strings.TrimSpace(query.Get("q"))}
+	if search.Terms == "" {
+		utils.ErrorResponse(w, "Search query is required", http.StatusBadRequest)
+		return
+	}
+
+	if raw := query.Get("category_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(w, "Invalid category_id", http.StatusBadRequest)
+			return
+		}
+		search.CategoryID = id
+	}
+
+	var err error
+	search.Limit, err = parseLimit(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		search.Offset, err = decodeOffsetCursor(raw)
+		if err != nil {
+			utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, hasMore, err := h.SearchRepo.Search(search)
+	if err != nil {
+		utils.ErrorResponse(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	// Snippets carry user content: escape it before adding the highlight markup
+	for i := range results {
+		snippet := html.EscapeString(results[i].Snippet)
+		snippet = strings.ReplaceAll(snippet, repository.SnippetMatchStart, "<mark>")
+		results[i].Snippet = strings.ReplaceAll(snippet, repository.SnippetMatchEnd, "</mark>")
+	}
+
+	response := SearchResponse{Results: results}
+	if hasMore {
+		response.NextCursor = encodeOffsetCursor(search.Offset + search.Limit)
+	}
+	utils.JSONResponse(w, response, http.StatusOK)
+}
@@ -0,0 +1,111 @@
+//go:build sqlite_fts5
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"forum/models"
+	"forum/repository"
+)
+
+func newTestSearchHandler(t *testing.T) *SearchHandler {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	db, err := models.InitDB()
+	if err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, stmt := range []string{
+		`INSERT INTO user (user_id, username, email) VALUES ('searcher', 'searcher', 'searcher@example.com')`,
+		`INSERT INTO posts (post_id, user_id, category_id, title, content) VALUES ('tofu-1', 'searcher', 1, 'Tofu <b>marinade</b>', 'Press the tofu first')`,
+		`INSERT INTO posts (post_id, user_id, category_id, title, content) VALUES ('tofu-2', 'searcher', 1, 'Crispy tofu', 'Cornstarch helps')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	return NewSearchHandler(repository.NewSearchRepository(db))
+}
+
+func search(t *testing.T, h *SearchHandler, query url.Values) (*httptest.ResponseRecorder, SearchResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.Search(rec, httptest.NewRequest(http.MethodGet, "/forum/api/search?"+query.Encode(), nil))
+	var resp SearchResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	return rec, resp
+}
+
+func TestSearchEscapesAndHighlightsSnippets(t *testing.T) {
+	h := newTestSearchHandler(t)
+
+	rec, resp := search(t, h, url.Values{"q": {"marinade"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", rec.Code, rec.Body)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("got %d results, want 1", len(resp.Results))
+	}
+	snippet := resp.Results[0].Snippet
+	if strings.Contains(snippet, "<b>") {
+		t.Errorf("snippet contains unescaped user markup: %q", snippet)
+	}
+	if !strings.Contains(snippet, "&lt;b&gt;<mark>marinade</mark>&lt;/b&gt;") {
+		t.Errorf("snippet %q does not escape the title and mark the match", snippet)
+	}
+}
+
+func TestSearchPagesWithCursor(t *testing.T) {
+	h := newTestSearchHandler(t)
+
+	seen := make(map[string]bool)
+	query := url.Values{"q": {"tofu"}, "limit": {"1"}}
+	for page := 1; ; page++ {
+		rec, resp := search(t, h, query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("page %d: got %d: %s", page, rec.Code, rec.Body)
+		}
+		for _, res := range resp.Results {
+			if seen[res.PostID] {
+				t.Errorf("page %d: %s returned twice", page, res.PostID)
+			}
+			seen[res.PostID] = true
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		if page == 3 {
+			t.Fatal("still returning a cursor after every result was seen")
+		}
+		query.Set("cursor", resp.NextCursor)
+	}
+	if len(seen) != 2 {
+		t.Errorf("paged through %d posts, want 2", len(seen))
+	}
+}
+
+func TestSearchRejectsBadRequests(t *testing.T) {
+	h := newTestSearchHandler(t)
+
+	for _, query := range []url.Values{
+		{"q": {"  "}},
+		{"q": {"tofu"}, "category_id": {"abc"}},
+		{"q": {"tofu"}, "cursor": {"not-a-cursor"}},
+	} {
+		if rec, _ := search(t, h, query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", query.Encode(), rec.Code)
+		}
+	}
+}
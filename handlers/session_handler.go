@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"forum/middleware"
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+// SessionHandler lets a user see and revoke their sessions on other devices
+type SessionHandler struct {
+	SessionRepo *repository.SessionRepository
+}
+
+// NewSessionHandler creates a new SessionHandler
+func NewSessionHandler(repo *repository.SessionRepository) *SessionHandler {
+	return &SessionHandler{SessionRepo: repo}
+}
+
+// SessionResponse describes one session without exposing its cookie token
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// ListSessions returns the authenticated user's active sessions, most recently used first
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetCurrentUser(r)
+	current := middleware.GetCurrentSession(r)
+	if user == nil || current == nil {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.SessionRepo.ListByUser(user.ID)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load sessions", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, sessionResponse(session, current.ID))
+	}
+	utils.JSONResponse(w, map[string]interface{}{"sessions": responses}, http.StatusOK)
+}
+
+// RevokeSession logs out one of the authenticated user's sessions by its ID.
+// Revoking the current session also clears the cookie.
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetCurrentUser(r)
+	current := middleware.GetCurrentSession(r)
+	if user == nil || current == nil {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := h.SessionRepo.DeleteForUser(user.ID, id); err != nil {
+		if err == repository.ErrSessionNotFound {
+			utils.ErrorResponse(w, "Session not found", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to revoke session", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if id == current.ID {
+		middleware.ClearSessionCookie(w)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions logs the authenticated user out everywhere except the current session
+func (h *SessionHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetCurrentUser(r)
+	current := middleware.GetCurrentSession(r)
+	if user == nil || current == nil {
+		utils.ErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	revoked, err := h.SessionRepo.DeleteOthers(user.ID, current.ID)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.JSONResponse(w, map[string]interface{}{"revoked": revoked}, http.StatusOK)
+}
+
+func sessionResponse(session models.Session, currentID string) SessionResponse {
+	return SessionResponse{
+		ID:         session.ID,
+		IPAddress:  session.IPAddress,
+		UserAgent:  session.UserAgent,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.ID == currentID,
+	}
+}
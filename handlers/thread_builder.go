@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"forum/models"
+	"forum/repository"
+)
+
+// threadBuilder turns repository rows into response trees. It loads categories,
+// comments and reactions in bulk, so the number of queries stays the same no
+// matter how many posts or comments are involved.
+type threadBuilder struct {
+	postRepo       *repository.PostRepository
+	commentRepo    *repository.CommentRepository // only needed when comments are embedded
+	reactionRepo   *repository.ReactionRepository
+	categoryNames  map[int]string
+	includeDeleted bool // show deleted comments as moderator placeholders
+}
+
+// buildPosts converts posts to responses in their original order. When
+// withComments is set, the first page of each post's comments is embedded too.
+func (b *threadBuilder) buildPosts(posts []models.PostWithUser, withComments bool) ([]PostResponse, error) {
+	responses := make([]PostResponse, 0, len(posts))
+	if len(posts) == 0 {
+		return responses, nil
+	}
+
+	postIDs := make([]string, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+
+	categoryIDs, err := b.postRepo.GetCategoryIDsForPosts(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := b.reactionRepo.GetReactionsForPosts(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments map[string][]CommentResponse
+	var nextComments map[string]*models.Cursor
+	if withComments {
+		var rows map[string][]models.CommentWithUser
+		rows, nextComments, err = b.commentRepo.GetFirstPagePerPost(postIDs, defaultPageSize, b.includeDeleted)
+		if err != nil {
+			return nil, err
+		}
+
+		var all []models.CommentWithUser
+		for _, id := range postIDs {
+			all = append(all, rows[id]...)
+		}
+		built, err := b.buildComments(all)
+		if err != nil {
+			return nil, err
+		}
+
+		comments = make(map[string][]CommentResponse, len(postIDs))
+		for i, comment := range all {
+			comments[comment.PostID] = append(comments[comment.PostID], built[i])
+		}
+	}
+
+	for _, post := range posts {
+		postResp := PostResponse{
+			ID:           post.ID,
+			UserID:       post.UserID,
+			Username:     post.Username,
+			CategoryID:   post.CategoryID,
+			CategoryName: b.categoryNames[post.CategoryID],
+			Categories:   []models.Category{},
+			Title:        post.Title,
+			Content:      post.Content,
+			CreatedAt:    post.CreatedAt,
+			UpdatedAt:    post.UpdatedAt,
+			Deleted:      post.Deleted,
+			Reactions:    reactionResponses(reactions[post.ID]),
+		}
+		for _, id := range categoryIDs[post.ID] {
+			postResp.Categories = append(postResp.Categories, models.Category{ID: id, Name: b.categoryNames[id]})
+		}
+		if withComments {
+			postResp.Comments = comments[post.ID]
+			if postResp.Comments == nil {
+				postResp.Comments = []CommentResponse{}
+			}
+			postResp.CommentsNextCursor = nextComments[post.ID].Encode()
+		}
+		responses = append(responses, postResp)
+	}
+	return responses, nil
+}
+
+// buildComments converts top-level comments to responses in their original
+// order, nesting every reply below them. Replies and reactions of all the
+// comments are loaded together.
+func (b *threadBuilder) buildComments(comments []models.CommentWithUser) ([]CommentResponse, error) {
+	responses := make([]CommentResponse, 0, len(comments))
+	if len(comments) == 0 {
+		return responses, nil
+	}
+
+	rootIDs := make([]string, len(comments))
+	for i, comment := range comments {
+		rootIDs[i] = comment.ID
+	}
+
+	replies, err := b.commentRepo.GetRepliesForComments(rootIDs, b.includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	all := append(append([]models.CommentWithUser{}, comments...), replies...)
+	allIDs := make([]string, len(all))
+	for i, comment := range all {
+		allIDs[i] = comment.ID
+	}
+
+	reactions, err := b.reactionRepo.GetReactionsForComments(allIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string][]models.CommentWithUser)
+	for _, reply := range replies {
+		children[*reply.ParentID] = append(children[*reply.ParentID], reply)
+	}
+
+	var build func(comment models.CommentWithUser) CommentResponse
+	build = func(comment models.CommentWithUser) CommentResponse {
+		resp := CommentResponse{
+			ID:        comment.ID,
+			ParentID:  comment.ParentID,
+			Depth:     comment.Depth,
+			UserID:    comment.UserID,
+			Username:  comment.Username,
+			Content:   comment.Content,
+			CreatedAt: comment.CreatedAt,
+			UpdatedAt: comment.UpdatedAt,
+			Deleted:   comment.Deleted,
+			Reactions: reactionResponses(reactions[comment.ID]),
+			Replies:   []CommentResponse{},
+		}
+		for _, child := range children[comment.ID] {
+			resp.Replies = append(resp.Replies, build(child))
+		}
+		return resp
+	}
+
+	for _, comment := range comments {
+		responses = append(responses, build(comment))
+	}
+	return responses, nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"forum/config"
+	"forum/middleware"
+	"forum/models"
+	"forum/repository"
+	"forum/utils"
+)
+
+// TrashHandler lets moderators review and restore deleted posts and comments
+type TrashHandler struct {
+	TrashRepo    *repository.TrashRepository
+	PostRepo     *repository.PostRepository
+	CategoryRepo *repository.CategoryRepository
+	Audit        *AuditLogger
+}
+
+// NewTrashHandler creates a new TrashHandler
+func NewTrashHandler(trashRepo *repository.TrashRepository, postRepo *repository.PostRepository, categoryRepo *repository.CategoryRepository, audit *AuditLogger) *TrashHandler {
+	return &TrashHandler{TrashRepo: trashRepo, PostRepo: postRepo, CategoryRepo: categoryRepo, Audit: audit}
+}
+
+// TrashResponse is one page of the trash
+type TrashResponse struct {
+	Items      []models.TrashedItem `json:"items"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// ListTrash lists deleted posts and comments (?limit=&cursor=), most recently
+// deleted first. Moderators see the categories they moderate, administrators everything.
+func (h *TrashHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		utils.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := middleware.GetCurrentUser(r)
+	scope := user.ID
+	if user.HasRole(models.RoleAdmin) {
+		scope = ""
+	}
+
+	items, next, err := h.TrashRepo.List(scope, page)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load trash", http.StatusInternalServerError)
+		return
+	}
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(config.TrashRetention)
+	}
+
+	utils.JSONResponse(w, TrashResponse{Items: items, NextCursor: next.Encode()}, http.StatusOK)
+}
+
+// Restore takes a post or comment out of the trash
+func (h *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.ErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		TargetType string `json:"target_type"`
+		TargetID   string `json:"target_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TargetType != models.TargetPost && req.TargetType != models.TargetComment {
+		utils.ErrorResponse(w, "Target type must be post or comment", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.TrashRepo.Get(req.TargetType, req.TargetID)
+	if err != nil {
+		if err == repository.ErrNotInTrash {
+			utils.ErrorResponse(w, "This "+req.TargetType+" is not in the trash", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to load trash", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	categoryIDs, err := h.PostRepo.GetCategoryIDs(item.PostID)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to load categories", http.StatusInternalServerError)
+		return
+	}
+	moderator := middleware.GetCurrentUser(r)
+	allowed, err := canModerate(h.CategoryRepo, moderator, categoryIDs)
+	if err != nil {
+		utils.ErrorResponse(w, "Failed to check permissions", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		utils.ErrorResponse(w, "You do not moderate this category", http.StatusForbidden)
+		return
+	}
+
+	if err := h.TrashRepo.Restore(item.TargetType, item.TargetID); err != nil {
+		if err == repository.ErrNotInTrash {
+			utils.ErrorResponse(w, "This "+req.TargetType+" is not in the trash", http.StatusNotFound)
+		} else {
+			utils.ErrorResponse(w, "Failed to restore "+req.TargetType, http.StatusInternalServerError)
+		}
+		return
+	}
+	h.Audit.Record(r, models.AuditEvent{Event: models.AuditRestore, TargetType: item.TargetType, TargetID: item.TargetID,
+		Details: "author_id=" + item.AuthorID})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FileMailer writes each message to an .eml file instead of sending it and
+// logs where it went. Meant for local development and testing.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer creates a new FileMailer
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{Dir: dir, From: from}
+}
+
+// Send saves the message to Dir
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, format(m.From, msg), 0o600); err != nil {
+		return err
+	}
+
+	slog.Info("Mail saved", "subject", msg.Subject, "to", msg.To, "path", path)
+	return nil
+}
@@ -0,0 +1,24 @@
+package mailer
+
+import "forum/config"
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns the mailer selected by the configuration: SMTP when a host is
+// configured, otherwise the file mailer for local testing
+func New() Mailer {
+	if config.SMTPHost != "" {
+		return NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	}
+	return NewFileMailer(config.MailDir, config.MailFrom)
+}
@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends mail through an SMTP server. net/smtp upgrades the
+// connection with STARTTLS when the server offers it.
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth // nil when the server needs no login
+	From string
+}
+
+// NewSMTPMailer creates a new SMTPMailer
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		Addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		From: from,
+	}
+	if username != "" {
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send delivers the message
+func (m *SMTPMailer) Send(msg Message) error {
+	return smtp.SendMail(m.Addr, m.Auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// format renders the message with the headers every mail needs
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -1,27 +1,86 @@
 package main
 
 import (
+	"database/sql"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"forum/config"
 	"forum/models"
+	"forum/repository"
 	"forum/routes"
+	"forum/utils"
 )
 
 func main() {
+	rebuildSearch := flag.Bool("rebuild-search", false, "rebuild the full-text search index before starting")
+	makeAdmin := flag.String("make-admin", "", "give the user with this email the administrator role before starting")
+	flag.Parse()
+
+	slog.SetDefault(utils.NewLogger(os.Stderr))
+
 	// Initialize database
 	db, err := models.InitDB()
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		slog.Error("Failed to initialize database", "error", err)
+		os.Exit(1)
 	}
 	defer db.Close()
 
+	if *rebuildSearch {
+		if err := models.RebuildSearchIndex(db); err != nil {
+			slog.Error("Failed to rebuild search index", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Search index rebuilt")
+	}
+
+	if *makeAdmin != "" {
+		if err := grantAdmin(db, *makeAdmin); err != nil {
+			slog.Error("Failed to make administrator", "email", *makeAdmin, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Administrator role granted", "email", *makeAdmin)
+	}
+
+	go purgeTrash(repository.NewTrashRepository(db))
+
 	// Setup routes
 	handler := routes.SetupRoutes(db)
 
 	// Start server
 	port := 8080
-	fmt.Printf("Server is running on http://localhost:%d\n", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), handler))
+	slog.Info("Server is running", "url", fmt.Sprintf("http://localhost:%d", port))
+	err = http.ListenAndServe(fmt.Sprintf(":%d", port), handler)
+	slog.Error("Server stopped", "error", err)
+	os.Exit(1)
+}
+
+// grantAdmin gives the administrator role to the user registered with email
+func grantAdmin(db *sql.DB, email string) error {
+	userRepo := repository.NewUserRepository(db)
+	user, err := userRepo.GetByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return err
+	}
+	return userRepo.SetRole(user.ID, models.RoleAdmin)
+}
+
+// purgeTrash permanently deletes posts and comments that have been in the
+// trash for longer than config.TrashRetention, every config.TrashPurgeInterval
+func purgeTrash(trashRepo *repository.TrashRepository) {
+	for {
+		purged, err := trashRepo.Purge(time.Now().Add(-config.TrashRetention))
+		if err != nil {
+			slog.Error("Failed to purge trash", "error", err)
+		} else if purged > 0 {
+			slog.Info("Trash purged", "count", purged)
+		}
+		time.Sleep(config.TrashPurgeInterval)
+	}
 }
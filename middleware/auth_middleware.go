@@ -2,23 +2,27 @@ package middleware
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 
 	"forum/models"
 	"forum/repository"
+	"forum/utils"
 )
 
 // Authentication middleware checks if the user is authenticated
 type AuthMiddleware struct {
 	SessionRepo *repository.SessionRepository
 	UserRepo    *repository.UserRepository
+	BanRepo     *repository.BanRepository
 }
 
 // NewAuthMiddleware creates a new AuthMiddleware
-func NewAuthMiddleware(sessionRepo *repository.SessionRepository, userRepo *repository.UserRepository) *AuthMiddleware {
+func NewAuthMiddleware(sessionRepo *repository.SessionRepository, userRepo *repository.UserRepository, banRepo *repository.BanRepository) *AuthMiddleware {
 	return &AuthMiddleware{
 		SessionRepo: sessionRepo,
 		UserRepo:    userRepo,
+		BanRepo:     banRepo,
 	}
 }
 
@@ -37,13 +41,7 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		session, err := m.SessionRepo.GetBySessionID(cookie.Value)
 		if err != nil {
 			// Invalid or expired session, clear the cookie and continue as unauthenticated
-			http.SetCookie(w, &http.Cookie{
-				Name:     "session_id",
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-			})
+			ClearSessionCookie(w)
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -52,25 +50,53 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		user, err := m.UserRepo.GetByID(session.UserID)
 		if err != nil {
 			// User not found, clear the cookie and continue as unauthenticated
-			http.SetCookie(w, &http.Cookie{
-				Name:     "session_id",
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-			})
+			ClearSessionCookie(w)
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Set user in context
+		// Banned users keep no sessions; one that slipped through is dropped here.
+		// Suspended users stay signed in but are read-only.
+		ban, err := m.BanRepo.GetActive(user.ID)
+		if err != nil && err != repository.ErrNotBanned {
+			// Can't tell whether the user may act, continue as unauthenticated
+			slog.Error("Failed to check bans", "user_id", user.ID, "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ban != nil && ban.Kind == models.BanKindBan {
+			m.SessionRepo.Delete(session.SessionID)
+			ClearSessionCookie(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Slide the session's expiry forward and refresh the cookie to match.
+		// Failing to renew is not fatal, the session is still valid for now.
+		if renewed, err := m.SessionRepo.Renew(session); err == nil && renewed {
+			SetSessionCookie(w, r, session)
+		}
+
+		// Set user and session in context
 		ctx := context.WithValue(r.Context(), "user", user)
+		ctx = context.WithValue(ctx, "session", session)
+		if ban != nil {
+			ctx = context.WithValue(ctx, "ban", ban)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// RequireAuth middleware ensures the user is authenticated
+// RequireAuth middleware ensures the user is authenticated and, for anything
+// but reads, not suspended
 func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
+	return m.RequireSignedIn(m.BlockSuspended(next))
+}
+
+// RequireSignedIn middleware ensures the user is authenticated. Suspended
+// users get through, so it guards what they may still do: managing their own
+// account and sessions.
+func (m *AuthMiddleware) RequireSignedIn(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := r.Context().Value("user")
 		if user == nil {
@@ -81,6 +107,37 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	})
 }
 
+// BlockSuspended middleware rejects state-changing requests from suspended
+// users and lets everything else through, including guests
+func (m *AuthMiddleware) BlockSuspended(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ban := GetCurrentBan(r); ban != nil && !isSafeMethod(r.Method) {
+			utils.ErrorResponse(w, ban.Describe(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole returns middleware that lets through authenticated users with
+// the given role or a more privileged one
+func (m *AuthMiddleware) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetCurrentUser(r)
+			if user == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !user.HasRole(role) {
+				utils.ErrorResponse(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			m.BlockSuspended(next).ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetCurrentUser returns the authenticated user from the context
 func GetCurrentUser(r *http.Request) *models.User {
 	user, ok := r.Context().Value("user").(*models.User)
@@ -88,4 +145,51 @@ func GetCurrentUser(r *http.Request) *models.User {
 		return nil
 	}
 	return user
-}
\ No newline at end of file
+}
+
+// GetCurrentSession returns the session the request was authenticated with
+func GetCurrentSession(r *http.Request) *models.Session {
+	session, ok := r.Context().Value("session").(*models.Session)
+	if !ok {
+		return nil
+	}
+	return session
+}
+
+// GetCurrentBan returns the suspension of the authenticated user, if any
+func GetCurrentBan(r *http.Request) *models.Ban {
+	ban, ok := r.Context().Value("ban").(*models.Ban)
+	if !ok {
+		return nil
+	}
+	return ban
+}
+
+// SetSessionCookie writes the session cookie. "Remember me" sessions get a
+// persistent cookie that expires with the session; others last until the
+// browser is closed.
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, session *models.Session) {
+	cookie := &http.Cookie{
+		Name:     "session_id",
+		Value:    session.SessionID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil, // Set Secure flag if TLS is enabled
+		SameSite: http.SameSiteStrictMode,
+	}
+	if session.RememberMe {
+		cookie.Expires = session.ExpiresAt
+	}
+	http.SetCookie(w, cookie)
+}
+
+// ClearSessionCookie tells the browser to drop the session cookie
+func ClearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_id",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
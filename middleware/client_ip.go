@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"forum/config"
+)
+
+// ClientIP resolves the address of the client that sent the request. The
+// forwarding headers are only believed when the direct peer is a trusted
+// proxy; the chain is then walked from the nearest hop outwards and the first
+// address that is not a trusted proxy is the client. Forwarded (RFC 7239)
+// takes precedence over X-Forwarded-For.
+func ClientIP(r *http.Request) string {
+	peer := remoteIP(r)
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || !isTrustedProxy(addr) {
+		return peer
+	}
+
+	chain := forwardedFor(r)
+	if len(chain) == 0 {
+		chain = xForwardedFor(r)
+	}
+
+	client := addr
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop, ok := parseHop(chain[i])
+		if !ok {
+			// Garbage or an obfuscated hop: trust nothing beyond it
+			break
+		}
+		client = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// remoteIP is the address of the direct peer, without the port
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr // fallback
+	}
+	return ip
+}
+
+func isTrustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range config.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the for= values of all Forwarded elements, nearest hop last
+func forwardedFor(r *http.Request) []string {
+	var hops []string
+	for _, header := range r.Header.Values("Forwarded") {
+		for _, element := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hops = append(hops, value)
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// xForwardedFor returns the X-Forwarded-For entries, nearest hop last
+func xForwardedFor(r *http.Request) []string {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseHop parses one hop address as written by either header: a bare IP,
+// an IP with port, or the quoted and bracketed forms Forwarded uses for IPv6
+func parseHop(value string) (netip.Addr, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
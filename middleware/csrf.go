@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+
+	"forum/utils"
+)
+
+const (
+	csrfCookieName = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// CSRFMiddleware protects cookie-authenticated, state-changing requests.
+// It checks that the request comes from the frontend (or the API itself)
+// using the Origin or Referer header, and that the X-CSRF-Token header
+// matches the csrf_token cookie (double-submit). Safe methods pass through.
+type CSRFMiddleware struct {
+	allowedOrigin string
+}
+
+// NewCSRFMiddleware creates a CSRFMiddleware trusting the given frontend origin
+func NewCSRFMiddleware(origin string) *CSRFMiddleware {
+	return &CSRFMiddleware{allowedOrigin: origin}
+}
+
+// Protect applies both the origin check and the token check
+func (c *CSRFMiddleware) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !c.originAllowed(r) {
+			utils.ErrorResponse(w, "Request origin not allowed", http.StatusForbidden)
+			return
+		}
+		if !validCSRFToken(r) {
+			utils.ErrorResponse(w, "Missing or invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CheckOrigin applies only the origin check. It is meant for login and
+// registration, which happen before the client holds a CSRF token.
+func (c *CSRFMiddleware) CheckOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isSafeMethod(r.Method) && !c.originAllowed(r) {
+			utils.ErrorResponse(w, "Request origin not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// originAllowed compares the Origin header, or the origin of the Referer when
+// Origin is absent, with the frontend and the API's own origin. Requests that
+// carry neither header do not come from a browser page and are left to the
+// token check.
+func (c *CSRFMiddleware) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		referer := r.Header.Get("Referer")
+		if referer == "" {
+			return true
+		}
+		u, err := url.Parse(referer)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return false
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return origin == c.allowedOrigin || origin == scheme+"://"+r.Host
+}
+
+// validCSRFToken checks that the header repeats the token from the cookie
+func validCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(csrfHeaderName)
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) == 1
+}
+
+// IssueCSRFToken sets a fresh CSRF cookie and returns its value. The cookie is
+// readable by scripts so the frontend can copy it into the X-CSRF-Token header.
+func IssueCSRFToken(w http.ResponseWriter, r *http.Request) string {
+	token := utils.GenerateToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/",
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+// EnsureCSRFToken returns the request's CSRF token, issuing one if it has none
+func EnsureCSRFToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	return IssueCSRFToken(w, r)
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"forum/config"
+	"forum/models"
+	"forum/repository"
+)
+
+type attemptInfo struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LoginThrottle tracks failed logins per account and per IP address. Once
+// either crosses its limit, logins are refused for a lockout period that
+// doubles with every further failure. Lockouts are recorded in the database.
+type LoginThrottle struct {
+	mu          sync.Mutex
+	accounts    map[string]*attemptInfo
+	ips         map[string]*attemptInfo
+	LockoutRepo *repository.LockoutRepository
+	Now         func() time.Time // clock, replaceable for testing
+}
+
+// NewLoginThrottle initializes the attempt maps and cleanup job
+func NewLoginThrottle(lockoutRepo *repository.LockoutRepository) *LoginThrottle {
+	t := &LoginThrottle{
+		accounts:    make(map[string]*attemptInfo),
+		ips:         make(map[string]*attemptInfo),
+		LockoutRepo: lockoutRepo,
+		Now:         time.Now,
+	}
+
+	// Periodic cleanup
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			t.cleanup()
+		}
+	}()
+
+	return t
+}
+
+// Check returns how long logins for the account from this request's IP are
+// still locked, or zero when an attempt is allowed
+func (t *LoginThrottle) Check(r *http.Request, account string) time.Duration {
+	now := t.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	wait := remaining(t.accounts[account], now)
+	if ipWait := remaining(t.ips[ClientIP(r)], now); ipWait > wait {
+		wait = ipWait
+	}
+	return wait
+}
+
+// Failure counts a failed login and returns how long further logins are
+// locked as a result, or zero when the limits have not been reached yet
+func (t *LoginThrottle) Failure(r *http.Request, account string) time.Duration {
+	now := t.Now()
+	ip := ClientIP(r)
+	var lockouts []models.LoginLockout
+
+	t.mu.Lock()
+	if lockout, ok := t.fail(t.accounts, account, config.LoginMaxAttempts, now); ok {
+		lockout.Scope, lockout.Identifier, lockout.IPAddress = models.LockoutScopeAccount, account, ip
+		lockouts = append(lockouts, lockout)
+	}
+	if lockout, ok := t.fail(t.ips, ip, config.LoginIPMaxAttempts, now); ok {
+		lockout.Scope, lockout.Identifier, lockout.IPAddress = models.LockoutScopeIP, ip, ip
+		lockouts = append(lockouts, lockout)
+	}
+	wait := remaining(t.accounts[account], now)
+	if ipWait := remaining(t.ips[ip], now); ipWait > wait {
+		wait = ipWait
+	}
+	t.mu.Unlock()
+
+	for _, lockout := range lockouts {
+		slog.Warn("Login lockout", "scope", lockout.Scope, "identifier", lockout.Identifier,
+			"failed_attempts", lockout.FailedAttempts, "locked_until", lockout.LockedUntil)
+		if err := t.LockoutRepo.Record(lockout); err != nil {
+			slog.Error("Failed to record login lockout", "error", err)
+		}
+	}
+	return wait
+}
+
+// Success clears the failed attempts of the account. The IP's count is kept so
+// that logging into one account does not reset guessing against others.
+func (t *LoginThrottle) Success(account string) {
+	t.mu.Lock()
+	delete(t.accounts, account)
+	t.mu.Unlock()
+}
+
+// fail records a failure under key and reports the lockout it triggers, if any.
+// Callers must hold t.mu.
+func (t *LoginThrottle) fail(entries map[string]*attemptInfo, key string, limit int, now time.Time) (models.LoginLockout, bool) {
+	info, exists := entries[key]
+	if !exists {
+		info = &attemptInfo{}
+		entries[key] = info
+	}
+
+	// Failures are forgotten once the window has passed since the last
+	// failure or the end of the last lockout, whichever is later
+	if now.Sub(lastActivity(info)) > config.LoginAttemptWindow {
+		info.failures = 0
+	}
+	info.failures++
+	info.lastFailure = now
+
+	if info.failures < limit {
+		return models.LoginLockout{}, false
+	}
+	info.lockedUntil = now.Add(lockoutDuration(info.failures - limit))
+	return models.LoginLockout{FailedAttempts: info.failures, LockedUntil: info.lockedUntil}, true
+}
+
+// lockoutDuration doubles the base lockout for every failure past the limit
+func lockoutDuration(extraFailures int) time.Duration {
+	d := config.LoginLockoutBase
+	for i := 0; i < extraFailures && d < config.LoginLockoutMax; i++ {
+		d *= 2
+	}
+	if d > config.LoginLockoutMax {
+		d = config.LoginLockoutMax
+	}
+	return d
+}
+
+func remaining(info *attemptInfo, now time.Time) time.Duration {
+	if info == nil || !info.lockedUntil.After(now) {
+		return 0
+	}
+	return info.lockedUntil.Sub(now)
+}
+
+func lastActivity(info *attemptInfo) time.Time {
+	if info.lockedUntil.After(info.lastFailure) {
+		return info.lockedUntil
+	}
+	return info.lastFailure
+}
+
+// Remove entries whose failures have been forgotten
+func (t *LoginThrottle) cleanup() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.Now()
+	for _, entries := range []map[string]*attemptInfo{t.accounts, t.ips} {
+		for key, info := range entries {
+			if now.Sub(lastActivity(info)) > config.LoginAttemptWindow {
+				delete(entries, key)
+			}
+		}
+	}
+}
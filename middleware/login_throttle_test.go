@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"forum/config"
+	"forum/repository"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestThrottle locks an account after 3 failures and an IP after 5, for
+// one minute doubling up to four, with a 15 minute attempt window
+func newTestThrottle(t *testing.T) (*LoginThrottle, *fakeClock, *sql.DB) {
+	t.Helper()
+	maxAttempts, ipMaxAttempts := config.LoginMaxAttempts, config.LoginIPMaxAttempts
+	window, base, lockoutMax := config.LoginAttemptWindow, config.LoginLockoutBase, config.LoginLockoutMax
+	t.Cleanup(func() {
+		config.LoginMaxAttempts, config.LoginIPMaxAttempts = maxAttempts, ipMaxAttempts
+		config.LoginAttemptWindow, config.LoginLockoutBase, config.LoginLockoutMax = window, base, lockoutMax
+	})
+	config.LoginMaxAttempts = 3
+	config.LoginIPMaxAttempts = 5
+	config.LoginAttemptWindow = 15 * time.Minute
+	config.LoginLockoutBase = time.Minute
+	config.LoginLockoutMax = 4 * time.Minute
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	db.SetMaxOpenConns(1) // every connection to :memory: is a new database
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(config.CreateLoginLockoutsTable); err != nil {
+		t.Fatalf("create lockouts table: %v", err)
+	}
+
+	clock := newFakeClock()
+	throttle := NewLoginThrottle(repository.NewLockoutRepository(db))
+	throttle.Now = clock.Now
+	return throttle, clock, db
+}
+
+func loginRequest(remoteAddr string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/forum/api/session/login", nil)
+	r.RemoteAddr = remoteAddr
+	return r
+}
+
+func countLockouts(t *testing.T, db *sql.DB, scope string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM login_lockouts WHERE scope = ?`, scope).Scan(&n); err != nil {
+		t.Fatalf("count lockouts: %v", err)
+	}
+	return n
+}
+
+func TestLoginThrottleLocksAtThreshold(t *testing.T) {
+	throttle, _, db := newTestThrottle(t)
+	r := loginRequest("192.0.2.1:1234")
+
+	for i := 1; i < config.LoginMaxAttempts; i++ {
+		if wait := throttle.Failure(r, "alice@example.com"); wait != 0 {
+			t.Fatalf("failure %d: locked for %v before reaching the limit", i, wait)
+		}
+	}
+	if wait := throttle.Check(r, "alice@example.com"); wait != 0 {
+		t.Fatalf("Check before the limit: got %v, want 0", wait)
+	}
+
+	if wait := throttle.Failure(r, "alice@example.com"); wait != time.Minute {
+		t.Fatalf("failure at the limit: locked for %v, want 1m", wait)
+	}
+	if wait := throttle.Check(r, "alice@example.com"); wait != time.Minute {
+		t.Errorf("Check while locked: got %v, want 1m", wait)
+	}
+	if n := countLockouts(t, db, "account"); n != 1 {
+		t.Errorf("recorded %d account lockouts, want 1", n)
+	}
+
+	// Other accounts are not affected by an account lockout
+	if wait := throttle.Check(r, "bob@example.com"); wait != 0 {
+		t.Errorf("Check for another account: got %v, want 0", wait)
+	}
+}
+
+func TestLoginThrottleDoublesUpToMax(t *testing.T) {
+	throttle, clock, db := newTestThrottle(t)
+	r := loginRequest("192.0.2.1:1234")
+
+	for i := 0; i < config.LoginMaxAttempts; i++ {
+		throttle.Failure(r, "alice@example.com")
+	}
+
+	// Each failure after a lockout ends doubles the next one, up to the maximum
+	wait := time.Minute
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		clock.Advance(wait)
+		if got := throttle.Check(r, "alice@example.com"); got != 0 {
+			t.Fatalf("Check after the lockout ended: got %v, want 0", got)
+		}
+		wait = throttle.Failure(r, "alice@example.com")
+		if wait != want {
+			t.Fatalf("lockout after %d failures: got %v, want %v", config.LoginMaxAttempts, wait, want)
+		}
+	}
+	if n := countLockouts(t, db, "account"); n != 4 {
+		t.Errorf("recorded %d account lockouts, want 4", n)
+	}
+}
+
+func TestLoginThrottleForgetsFailuresAfterWindow(t *testing.T) {
+	throttle, clock, _ := newTestThrottle(t)
+	r := loginRequest("192.0.2.1:1234")
+
+	for i := 1; i < config.LoginMaxAttempts; i++ {
+		throttle.Failure(r, "alice@example.com")
+	}
+	clock.Advance(config.LoginAttemptWindow + time.Second)
+
+	// The count starts over, so this failure is the first again
+	if wait := throttle.Failure(r, "alice@example.com"); wait != 0 {
+		t.Fatalf("failure after the window: locked for %v, want 0", wait)
+	}
+
+	// Reaching the limit again locks the account; a full window after the
+	// lockout ends, the count starts over as well
+	for i := 1; i < config.LoginMaxAttempts; i++ {
+		throttle.Failure(r, "alice@example.com")
+	}
+	clock.Advance(time.Minute + config.LoginAttemptWindow + time.Second)
+	if wait := throttle.Failure(r, "alice@example.com"); wait != 0 {
+		t.Fatalf("failure after the window following a lockout: locked for %v, want 0", wait)
+	}
+}
+
+func TestLoginThrottleSuccessClearsAccount(t *testing.T) {
+	throttle, _, _ := newTestThrottle(t)
+	r := loginRequest("192.0.2.1:1234")
+
+	for i := 1; i < config.LoginMaxAttempts; i++ {
+		throttle.Failure(r, "alice@example.com")
+	}
+	throttle.Success("alice@example.com")
+	if wait := throttle.Failure(r, "alice@example.com"); wait != 0 {
+		t.Fatalf("failure after a successful login: locked for %v, want 0", wait)
+	}
+}
+
+func TestLoginThrottleLocksIP(t *testing.T) {
+	throttle, _, db := newTestThrottle(t)
+	r := loginRequest("192.0.2.1:1234")
+
+	// Guessing one password each for many accounts still trips the IP limit
+	accounts := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	for i, account := range accounts {
+		wait := throttle.Failure(r, account)
+		if i < len(accounts)-1 && wait != 0 {
+			t.Fatalf("failure %d: locked for %v before reaching the IP limit", i+1, wait)
+		}
+		if i == len(accounts)-1 && wait != time.Minute {
+			t.Fatalf("failure at the IP limit: locked for %v, want 1m", wait)
+		}
+	}
+	if n := countLockouts(t, db, "ip"); n != 1 {
+		t.Errorf("recorded %d ip lockouts, want 1", n)
+	}
+
+	if wait := throttle.Check(r, "someone-else@example.com"); wait != time.Minute {
+		t.Errorf("Check for a new account from the locked IP: got %v, want 1m", wait)
+	}
+	if wait := throttle.Check(loginRequest("192.0.2.2:1234"), "someone-else@example.com"); wait != 0 {
+		t.Errorf("Check from another IP: got %v, want 0", wait)
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"forum/config"
+	"forum/utils"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is a token-bucket limiter for one route policy. Each client
+// (the user when logged in, the IP address otherwise) gets a bucket of
+// limit.Requests tokens that refills evenly over limit.Window; a request
+// takes one token. Only requests with one of the given methods are counted.
+type RateLimiter struct {
+	mu      sync.Mutex
+	name    string
+	limit   config.RateLimit
+	methods []string
+	buckets map[string]*bucket
+	Now     func() time.Time // clock, replaceable for testing
+}
+
+// NewRateLimiter initializes the bucket map and cleanup job. With no methods
+// every request is counted.
+func NewRateLimiter(name string, limit config.RateLimit, methods ...string) *RateLimiter {
+	rl := &RateLimiter{
+		name:    name,
+		limit:   limit,
+		methods: methods,
+		buckets: make(map[string]*bucket),
+		Now:     time.Now,
+	}
+
+	// Periodic cleanup
+	go func() {
+		for {
+			time.Sleep(limit.Window)
+			rl.cleanup()
+		}
+	}()
+
+	return rl
+}
+
+// Limit rejects requests over the limit with 429 and reports the client's
+// remaining allowance in X-RateLimit-* headers
+func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(rl.methods) > 0 && !slices.Contains(rl.methods, r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, remaining, reset, retryAfter := rl.take(rl.clientKey(r))
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rl.limit.Requests))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(seconds(reset)))
+
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds(retryAfter)))
+			utils.ErrorResponse(w, fmt.Sprintf("Too many %s requests. Please slow down.", rl.name), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take refills the client's bucket and tries to take a token from it. It
+// returns the tokens left, the time until the bucket is full again and, when
+// the request is refused, the time until the next token.
+func (rl *RateLimiter) take(key string) (allowed bool, remaining int, reset, retryAfter time.Duration) {
+	now := rl.Now()
+	capacity := float64(rl.limit.Requests)
+	perToken := rl.limit.Window / time.Duration(rl.limit.Requests)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b, exists := rl.buckets[key]
+	if !exists {
+		b = &bucket{tokens: capacity, last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		allowed = true
+	} else {
+		retryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	reset = time.Duration((capacity - b.tokens) * float64(perToken))
+	return allowed, int(b.tokens), reset, retryAfter
+}
+
+// clientKey identifies the client: the user when authenticated, the IP otherwise
+func (rl *RateLimiter) clientKey(r *http.Request) string {
+	if user := GetCurrentUser(r); user != nil {
+		return "user:" + user.ID
+	}
+	return "ip:" + ClientIP(r)
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// Remove buckets that have refilled completely
+func (rl *RateLimiter) cleanup() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.Now()
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) >= rl.limit.Window {
+			delete(rl.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"forum/config"
+	"forum/models"
+)
+
+// fakeClock is a manually advanced clock for RateLimiter.Now and LoginThrottle.Now
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestLimiter allows 3 requests per 30 seconds, one token every 10 seconds
+func newTestLimiter(clock *fakeClock, methods ...string) http.Handler {
+	rl := NewRateLimiter("test", config.RateLimit{Requests: 3, Window: 30 * time.Second}, methods...)
+	rl.Now = clock.Now
+	return rl.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func limitedRequest(method, remoteAddr string, user *models.User) *http.Request {
+	r := httptest.NewRequest(method, "/", nil)
+	r.RemoteAddr = remoteAddr
+	if user != nil {
+		r = r.WithContext(context.WithValue(r.Context(), "user", user))
+	}
+	return r
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	h := newTestLimiter(newFakeClock())
+
+	for i, want := range []struct{ remaining, reset string }{
+		{"2", "10"},
+		{"1", "20"},
+		{"0", "30"},
+	} {
+		rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d: got %d, want 204", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("request %d: X-RateLimit-Limit = %q, want 3", i+1, got)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != want.remaining {
+			t.Errorf("request %d: X-RateLimit-Remaining = %q, want %s", i+1, got, want.remaining)
+		}
+		if got := rec.Header().Get("X-RateLimit-Reset"); got != want.reset {
+			t.Errorf("request %d: X-RateLimit-Reset = %q, want %s", i+1, got, want.reset)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "" {
+			t.Errorf("request %d: unexpected Retry-After %q", i+1, got)
+		}
+	}
+}
+
+func TestRateLimiterRejectsWhenEmpty(t *testing.T) {
+	clock := newFakeClock()
+	h := newTestLimiter(clock)
+	for i := 0; i < 3; i++ {
+		serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil))
+	}
+
+	clock.Advance(4 * time.Second)
+	rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("got %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "6" {
+		t.Errorf("Retry-After = %q, want 6", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+}
+
+func TestRateLimiterRefillsOverTime(t *testing.T) {
+	clock := newFakeClock()
+	h := newTestLimiter(clock)
+	for i := 0; i < 3; i++ {
+		serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil))
+	}
+
+	// One token comes back every 10 seconds
+	clock.Advance(10 * time.Second)
+	if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("after one token refilled: got %d, want 204", rec.Code)
+	}
+	if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil)); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("after using the refilled token: got %d, want 429", rec.Code)
+	}
+
+	// The bucket never holds more than its capacity
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil)); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d after a full refill: got %d, want 204", i+1, rec.Code)
+		}
+	}
+	if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil)); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request 4 after a full refill: got %d, want 429", rec.Code)
+	}
+}
+
+func TestRateLimiterSeparatesUsersAndIPs(t *testing.T) {
+	h := newTestLimiter(newFakeClock())
+	alice := &models.User{ID: "alice"}
+	bob := &models.User{ID: "bob"}
+
+	// Exhaust the anonymous bucket of the IP
+	for i := 0; i < 3; i++ {
+		serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil))
+	}
+	if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil)); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("ip bucket: got %d, want 429", rec.Code)
+	}
+
+	// Signed-in users from the same IP are counted on their own
+	for i := 0; i < 3; i++ {
+		if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", alice)); rec.Code != http.StatusNoContent {
+			t.Fatalf("alice request %d: got %d, want 204", i+1, rec.Code)
+		}
+	}
+	if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", alice)); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("alice request 4: got %d, want 429", rec.Code)
+	}
+	if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", bob)); rec.Code != http.StatusNoContent {
+		t.Fatalf("bob: got %d, want 204", rec.Code)
+	}
+
+	// Another IP has its own bucket
+	if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.2:1234", nil)); rec.Code != http.StatusNoContent {
+		t.Fatalf("other ip: got %d, want 204", rec.Code)
+	}
+}
+
+func TestRateLimiterIgnoresOtherMethods(t *testing.T) {
+	h := newTestLimiter(newFakeClock(), http.MethodPost)
+
+	for i := 0; i < 10; i++ {
+		rec := serve(h, limitedRequest(http.MethodGet, "192.0.2.1:1234", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("GET %d: got %d, want 204", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("X-RateLimit-Limit"); got != "" {
+			t.Fatalf("GET %d: uncounted request got X-RateLimit-Limit %q", i+1, got)
+		}
+	}
+
+	// The GETs took no tokens
+	if rec := serve(h, limitedRequest(http.MethodPost, "192.0.2.1:1234", nil)); rec.Header().Get("X-RateLimit-Remaining") != "2" {
+		t.Fatalf("first POST: X-RateLimit-Remaining = %q, want 2", rec.Header().Get("X-RateLimit-Remaining"))
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Audit events
+const (
+	AuditRegister          = "register"
+	AuditLogin             = "login"
+	AuditLoginFailed       = "login_failed"
+	AuditLoginLockout      = "login_lockout"
+	AuditLogout            = "logout"
+	AuditPasswordChange    = "password_change"
+	AuditPasswordReset     = "password_reset"
+	AuditRoleChange        = "role_change"
+	AuditModeratorAssigned = "moderator_assigned"
+	AuditModeratorRemoved  = "moderator_removed"
+	AuditBan               = "ban"
+	AuditBanLifted         = "ban_lifted"
+	AuditReportsResolved   = "reports_resolved"
+	AuditDelete            = "delete"
+	AuditRestore           = "restore"
+
+	// Edits of other users' content by moderators and administrators
+	AuditPostModeratorEdit    = "post.moderator_edit"
+	AuditCommentModeratorEdit = "comment.moderator_edit"
+)
+
+// Audit targets besides TargetPost and TargetComment
+const (
+	AuditTargetUser     = "user"
+	AuditTargetCategory = "category"
+	AuditTargetBan      = "ban"
+)
+
+// AuditEvent is one entry of the append-only audit log
+type AuditEvent struct {
+	ID         int64     `json:"id"`
+	Event      string    `json:"event"`
+	ActorID    string    `json:"actor_id,omitempty"` // empty for anonymous requests such as failed logins
+	TargetType string    `json:"target_type,omitempty"`
+	TargetID   string    `json:"target_id,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditFilter narrows down the audit log; zero values mean "no filter"
+type AuditFilter struct {
+	Event      string
+	ActorID    string
+	TargetType string
+	TargetID   string
+	IPAddress  string
+	Since      *time.Time
+	Until      *time.Time
+}
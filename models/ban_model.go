@@ -0,0 +1,38 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of account restrictions
+const (
+	BanKindBan        = "ban"        // no login at all
+	BanKindSuspension = "suspension" // read-only account
+)
+
+// Ban restricts a user's account, permanently or until ExpiresAt
+type Ban struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Kind      string     `json:"kind"`
+	Reason    string     `json:"reason"`
+	IssuedBy  string     `json:"issued_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil for permanent restrictions
+	LiftedAt  *time.Time `json:"lifted_at,omitempty"`
+	LiftedBy  *string    `json:"lifted_by,omitempty"`
+}
+
+// Describe explains the restriction to the affected user
+func (b *Ban) Describe() string {
+	state := "banned"
+	if b.Kind == BanKindSuspension {
+		state = "suspended"
+	}
+	until := "permanently"
+	if b.ExpiresAt != nil {
+		until = "until " + b.ExpiresAt.UTC().Format("2006-01-02 15:04 MST")
+	}
+	return fmt.Sprintf("Your account is %s %s: %s", state, until, b.Reason)
+}
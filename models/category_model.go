@@ -1,7 +1,17 @@
 package models
 
+import "time"
+
 // Category represents a discussion category
 type Category struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
-}
\ No newline at end of file
+}
+
+// CategoryModerator is a user assigned to moderate a category
+type CategoryModerator struct {
+	CategoryID int       `json:"category_id"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -5,19 +5,26 @@ import "time"
 type Comment struct {
 	ID        string     `json:"id"`
 	PostID    string     `json:"post_id"`
+	ParentID  *string    `json:"parent_comment_id,omitempty"` // nil for top-level comments
+	Depth     int        `json:"depth"`                       // 0 for top-level comments
 	UserID    string     `json:"user_id"`
 	Content   string     `json:"content"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Hidden    bool       `json:"-"` // hidden by a moderator
 }
 
 
 // CommentWithUser is a comment along with the username of its author
 type CommentWithUser struct {
-	ID        string    `json:"id"`
-	PostID    string    `json:"post_id"`
-	UserID    string    `json:"user_id"`
-	Username  string    `json:"username"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string     `json:"id"`
+	PostID    string     `json:"post_id"`
+	ParentID  *string    `json:"parent_comment_id,omitempty"`
+	Depth     int        `json:"depth"`
+	UserID    string     `json:"user_id"`
+	Username  string     `json:"username"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"` // content replaced by a placeholder
 }
\ No newline at end of file
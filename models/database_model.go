@@ -3,7 +3,9 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"forum/config"
+	"forum/utils"
 	"os"
 	"path/filepath"
 
@@ -59,14 +61,59 @@ func InitDB() (*sql.DB, error) {
 			db.Close()
 			return nil, fmt.Errorf("failed to insert mock data: %v", err)
 		}
-		fmt.Println("Database initialized successfully.")
+		// A fresh database already has the latest schema
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(config.Migrations))); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to set schema version: %v", err)
+		}
+		slog.Info("Database initialized")
 	} else {
-		fmt.Println("Database already exists. Skipping initialization.")
+		slog.Info("Database already exists, skipping initialization")
+		if err := migrateSchema(db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %v", err)
+		}
+	}
+
+	if err := setupSearch(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set up search: %v", err)
 	}
 
 	return db, nil
 }
 
+// migrateSchema applies every migration newer than the database's user_version
+func migrateSchema(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %v", err)
+	}
+
+	for i := version; i < len(config.Migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %v", err)
+		}
+
+		if _, err := tx.Exec(config.Migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %v", i+1, err)
+		}
+		// PRAGMA does not accept bound parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to set schema version %d: %v", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %v", i+1, err)
+		}
+		slog.Info("Applied database migration", "version", i+1)
+	}
+	return nil
+}
+
 func createTables(db *sql.DB) error {
 	// Start a transaction for atomicity
 	tx, err := db.Begin()
@@ -82,8 +129,17 @@ func createTables(db *sql.DB) error {
 		config.CreateSessionsTable,
 		config.CreateCategoriesTable,
 		config.CreatePostsTable,
+		config.CreatePostCategoriesTable,
 		config.CreateCommentsTable,
 		config.CreateReactionsTable,
+		config.CreateLoginLockoutsTable,
+		config.CreateUserTokensTable,
+		config.CreateCategoryModeratorsTable,
+		config.CreateModerationActionsTable,
+		config.CreateReportsTable,
+		config.CreateUserBansTable,
+		config.CreateAuditLogTable,
+		config.CreateAuditLogTriggers,
 	}
 
 	// Execute each table creation statement
@@ -112,9 +168,28 @@ func createIndexes(db *sql.DB) error {
 		config.IdxPostsCategoryID,
 		config.IdxCommentsPostID,
 		config.IdxCommentsUserID,
+		config.IdxCommentsParentID,
 		config.IdxReactionsUserID,
 		config.IdxReactionsPostID,
 		config.IdxReactionsCommentID,
+		config.IdxReactionsUserPost,
+		config.IdxReactionsUserComment,
+		config.IdxPostCategoriesCategoryID,
+		config.IdxSessionsUserID,
+		config.IdxLoginLockoutsCreatedAt,
+		config.IdxUserTokensUserID,
+		config.IdxCategoryModeratorsUserID,
+		config.IdxReportsStatusTarget,
+		config.IdxModerationActionsTarget,
+		config.IdxModerationActionsUser,
+		config.IdxUserBansUserID,
+		config.IdxPostsDeletedAt,
+		config.IdxCommentsDeletedAt,
+		config.IdxAuditLogActor,
+		config.IdxAuditLogTarget,
+		config.IdxPostsCreatedAt,
+		config.IdxPostsUserCreatedAt,
+		config.IdxCommentsPostCreatedAt,
 	}
 
 	// Execute each index creation statement
@@ -157,13 +232,16 @@ func populateCategories(db *sql.DB, categories []string) error {
 		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
-	fmt.Println("Categories populated (duplicates ignored if existed).")
+	slog.Info("Categories populated")
 	return nil
 }
 
+// MockUserPassword is the password of the demo users created by InsertMockData
+const MockUserPassword = "password123"
+
 // InsertMockData inserts mock users, categories, posts, comments, and reactions for testing/demo purposes.
 func InsertMockData(db *sql.DB) error {
-	now := "2025-05-26T14:30:00"
+	now := "2025-05-26T14:30:00.000Z"
 
 	users := map[string]string{
 		"Alice": "alice@example.com",
@@ -195,6 +273,12 @@ func InsertMockData(db *sql.DB) error {
 		"BobOnAlice": "d1e2f3g4-h5i6-7890-1234-dddddddddddd",
 	}
 
+	// Every mock user logs in with MockUserPassword
+	passwordHash, err := utils.HashPassword(MockUserPassword)
+	if err != nil {
+		return fmt.Errorf("hash mock password: %v", err)
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return err
@@ -203,8 +287,8 @@ func InsertMockData(db *sql.DB) error {
 
 	// Insert users
 	for name, email := range users {
-		_, err := tx.Exec(`INSERT OR IGNORE INTO user (user_id, username, email, created_at) VALUES (?, ?, ?, ?)`,
-			userIDs[name], name, email, now)
+		_, err := tx.Exec(`INSERT OR IGNORE INTO user (user_id, username, email, email_verified_at, created_at) VALUES (?, ?, ?, ?, ?)`,
+			userIDs[name], name, email, now, now)
 		if err != nil {
 			return fmt.Errorf("insert user %s: %v", name, err)
 		}
@@ -213,7 +297,7 @@ func InsertMockData(db *sql.DB) error {
 	// Insert user passwords
 	for name := range users {
 		_, err := tx.Exec(`INSERT OR IGNORE INTO user_auth (user_id, password_hash) VALUES (?, ?)`,
-			userIDs[name], "hashed_password_123")
+			userIDs[name], passwordHash)
 		if err != nil {
 			return fmt.Errorf("insert password for user %s: %v", name, err)
 		}
@@ -240,6 +324,13 @@ func InsertMockData(db *sql.DB) error {
 		return fmt.Errorf("insert Bob post: %v", err)
 	}
 
+	// Link posts to their categories
+	_, err = tx.Exec(`INSERT INTO post_categories (post_id, category_id) VALUES (?, ?), (?, ?)`,
+		posts["AlicePost"], categories["Software Development"], posts["BobPost"], categories["Pets"])
+	if err != nil {
+		return fmt.Errorf("insert post categories: %v", err)
+	}
+
 	// Insert comments
 	_, err = tx.Exec(`INSERT INTO comments (comment_id, post_id, user_id, content, created_at) VALUES (?, ?, ?, ?, ?)`,
 		comments["AliceOnBob"], posts["BobPost"], userIDs["Alice"], "Nice post, Bob!", now)
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Lockout scopes
+const (
+	LockoutScopeAccount = "account"
+	LockoutScopeIP      = "ip"
+)
+
+// LoginLockout records that logins for an account or from an IP address were
+// blocked after repeated failures
+type LoginLockout struct {
+	ID             int64     `json:"id"`
+	Scope          string    `json:"scope"`
+	Identifier     string    `json:"identifier"`
+	IPAddress      string    `json:"ip_address"`
+	FailedAttempts int       `json:"failed_attempts"`
+	LockedUntil    time.Time `json:"locked_until"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// LockoutFilter narrows down the lockout history; zero values mean "no filter"
+type LockoutFilter struct {
+	Scope      string
+	Identifier string // account email or IP address
+}
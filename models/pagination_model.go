@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last row of a page for keyset pagination on (created_at, id).
+// CreatedAt holds the raw created_at text stored in SQLite so that comparisons
+// match the ORDER BY exactly; paged timestamps are stored in UTC with
+// millisecond precision, so that this text order is time order. Count is the leading sort key of listings ranked
+// by a count, such as the report queue.
+type Cursor struct {
+	CreatedAt string `json:"c"`
+	ID        string `json:"i"`
+	Count     int    `json:"n,omitempty"`
+}
+
+// Page describes which slice of a listing to return
+type Page struct {
+	Limit int
+	After *Cursor // nil for the first page
+}
+
+// Encode turns the cursor into an opaque URL-safe token
+func (c *Cursor) Encode() string {
+	if c == nil {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token produced by Cursor.Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.CreatedAt == "" || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
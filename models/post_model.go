@@ -3,23 +3,35 @@ package models
 import "time"
 
 type Post struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	CategoryID  int        `json:"category_id"`  // primary category
+	CategoryIDs []int      `json:"category_ids"` // every category the post is listed under
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	Hidden      bool       `json:"-"` // hidden by a moderator
+}
+
+
+// PostWithUser is a post along with the username of its author
+type PostWithUser struct {
 	ID         string     `json:"id"`
 	UserID     string     `json:"user_id"`
+	Username   string     `json:"username"`
 	CategoryID int        `json:"category_id"`
-    Title      string     `json:"title"`
+	Title      string     `json:"title"`
 	Content    string     `json:"content"`
 	CreatedAt  time.Time  `json:"created_at"`
 	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	Deleted    bool       `json:"deleted,omitempty"` // a "[removed]" placeholder, moderators only
 }
 
-
-// PostWithUser is a post along with the username of its author
-type PostWithUser struct {
-	ID         string    `json:"id"`
-	UserID     string    `json:"user_id"`
-	Username   string    `json:"username"`
-	CategoryID int       `json:"category_id"`
-	Title      string    `json:"title"`
-	Content    string    `json:"content"`
-	CreatedAt  time.Time `json:"created_at"`
+// PostFilter narrows down post listings; zero values mean "no filter"
+type PostFilter struct {
+	CategoryID     int    // only posts listed under this category
+	AuthorID       string // only posts written by this user
+	LikedBy        string // only posts this user liked or loved
+	IncludeDeleted bool   // list deleted posts as placeholders, for moderators
 }
@@ -2,9 +2,22 @@ package models
 
 import "time"
 
+// Reaction types accepted by the reactions.reaction_type CHECK constraint
+const (
+	ReactionLike    = 1
+	ReactionDislike = 2
+	ReactionLove    = 3
+)
+
+// Reaction targets
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+)
+
 type Reaction struct {
 	UserID    string    `json:"user_id"`
-	Type      int       `json:"reaction_type"` // 1 = Like, 2 = Love, etc.
+	Type      int       `json:"reaction_type"` // 1 = Like, 2 = Dislike, 3 = Love
 	CommentID *string   `json:"comment_id,omitempty"`
 	PostID    *string   `json:"post_id,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
@@ -19,4 +32,16 @@ type ReactionWithUser struct {
 	PostID       *string   `json:"post_id,omitempty"`
 	CommentID    *string   `json:"comment_id,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
-}
\ No newline at end of file
+}
+
+// ReactionCounts holds the number of reactions of each type on a post or comment
+type ReactionCounts struct {
+	Like    int `json:"like"`
+	Dislike int `json:"dislike"`
+	Love    int `json:"love"`
+}
+
+// IsValidReactionType reports whether t is one of the supported reaction types
+func IsValidReactionType(t int) bool {
+	return t == ReactionLike || t == ReactionDislike || t == ReactionLove
+}
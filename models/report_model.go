@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Report states
+const (
+	ReportOpen     = "open"
+	ReportResolved = "resolved"
+)
+
+// Moderation actions that resolve the reports on a post or comment
+const (
+	ModerationDismiss = "dismiss" // the content is fine
+	ModerationHide    = "hide"    // the content is hidden from everyone
+	ModerationWarn    = "warn"    // the author receives a warning
+)
+
+// IsValidModerationAction reports whether action is one of the supported actions
+func IsValidModerationAction(action string) bool {
+	return action == ModerationDismiss || action == ModerationHide || action == ModerationWarn
+}
+
+// Report is one user's complaint about a post or comment
+type Report struct {
+	ID               string     `json:"id"`
+	ReporterID       string     `json:"reporter_id"`
+	ReporterUsername string     `json:"reporter_username,omitempty"`
+	TargetType       string     `json:"target_type"` // TargetPost or TargetComment
+	TargetID         string     `json:"target_id"`
+	Reason           string     `json:"reason"`
+	Status           string     `json:"status"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
+	ActionID         *string    `json:"action_id,omitempty"` // the moderation action that resolved it
+}
+
+// ReportedTarget groups the open reports on one post or comment for the moderation queue
+type ReportedTarget struct {
+	TargetType     string   `json:"target_type"`
+	TargetID       string   `json:"target_id"`
+	PostID         string   `json:"post_id"`
+	AuthorID       string   `json:"author_id"`
+	AuthorUsername string   `json:"author_username"`
+	Title          string   `json:"title,omitempty"` // posts only
+	Content        string   `json:"content"`
+	Hidden         bool     `json:"hidden"`
+	ReportCount    int      `json:"report_count"`
+	Reports        []Report `json:"reports"`
+}
+
+// ModerationAction records a moderator's decision on a post or comment
+type ModerationAction struct {
+	ID           string    `json:"id"`
+	ModeratorID  string    `json:"moderator_id"`
+	Action       string    `json:"action"`
+	TargetType   string    `json:"target_type"`
+	TargetID     string    `json:"target_id"`
+	TargetUserID string    `json:"target_user_id"` // author of the content
+	Note         string    `json:"note,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ModerationFilter narrows down the moderation history; zero values mean "no filter"
+type ModerationFilter struct {
+	TargetType   string
+	TargetID     string
+	TargetUserID string
+	ModeratorID  string
+	Action       string
+}
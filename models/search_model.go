@@ -0,0 +1,110 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"forum/config"
+)
+
+// SearchResult is a post or comment matching a full-text query
+type SearchResult struct {
+	Kind      string    `json:"kind"` // "post" or "comment"
+	PostID    string    `json:"post_id"`
+	CommentID *string   `json:"comment_id,omitempty"`
+	PostTitle string    `json:"post_title"`
+	Username  string    `json:"username"`
+	Snippet   string    `json:"snippet"` // HTML-escaped, matches wrapped in <mark>
+	Score     float64   `json:"score"`   // bm25, lower is more relevant
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SearchQuery describes a full-text search request
+type SearchQuery struct {
+	Terms      string
+	CategoryID int // 0 for every category
+	Limit      int
+	Offset     int
+}
+
+// SearchAvailable reports whether this binary's SQLite was built with FTS5
+// (go build -tags sqlite_fts5)
+func SearchAvailable(db *sql.DB) bool {
+	var enabled bool
+	err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled)
+	return err == nil && enabled
+}
+
+// setupSearch creates the full-text index and its triggers. An index that is
+// new, or whose triggers were missing, is rebuilt from the existing posts and
+// comments. Without FTS5 the triggers are dropped so writes keep working.
+func setupSearch(db *sql.DB) error {
+	if !SearchAvailable(db) {
+		for name := range config.SearchTriggers {
+			if _, err := db.Exec("DROP TRIGGER IF EXISTS " + name); err != nil {
+				return fmt.Errorf("failed to drop search trigger %s: %v", name, err)
+			}
+		}
+		slog.Warn("SQLite was built without FTS5; search is disabled (build with -tags sqlite_fts5)")
+		return nil
+	}
+
+	var existing int
+	names := make([]string, 0, len(config.SearchTriggers))
+	args := make([]interface{}, 0, len(config.SearchTriggers))
+	for name := range config.SearchTriggers {
+		names = append(names, "?")
+		args = append(args, name)
+	}
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (`+strings.Join(names, ", ")+`)`,
+		args...).Scan(&existing)
+	if err != nil {
+		return fmt.Errorf("failed to inspect search triggers: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{config.CreateSearchDocsTable, config.CreateSearchIndexTable}
+	for _, stmt := range config.SearchTriggers {
+		statements = append(statements, stmt)
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create search index: %v\nSQL: %s", err, stmt)
+		}
+	}
+
+	if existing < len(config.SearchTriggers) {
+		if _, err := tx.Exec(config.RebuildSearchIndex); err != nil {
+			return fmt.Errorf("failed to build search index: %v", err)
+		}
+		slog.Info("Search index built")
+	}
+
+	return tx.Commit()
+}
+
+// RebuildSearchIndex repopulates the full-text index from posts and comments
+func RebuildSearchIndex(db *sql.DB) error {
+	if !SearchAvailable(db) {
+		return fmt.Errorf("SQLite was built without FTS5 (build with -tags sqlite_fts5)")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(config.RebuildSearchIndex); err != nil {
+		return fmt.Errorf("failed to rebuild search index: %v", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TrashedItem is a deleted post or comment that moderators can still restore
+type TrashedItem struct {
+	TargetType     string    `json:"target_type"` // TargetPost or TargetComment
+	TargetID       string    `json:"target_id"`
+	PostID         string    `json:"post_id"`
+	AuthorID       string    `json:"author_id"`
+	AuthorUsername string    `json:"author_username"`
+	Title          string    `json:"title,omitempty"` // posts only
+	Content        string    `json:"content"`
+	DeletedAt      time.Time `json:"deleted_at"`
+	DeletedBy      string    `json:"deleted_by"`
+	PurgeAt        time.Time `json:"purge_at"` // when the item is deleted for good
+}
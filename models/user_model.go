@@ -1,15 +1,41 @@
 package models
 
 import (
+	"log/slog"
 	"time"
 )
 
+// User roles, from least to most privileged. Moderators moderate the
+// categories assigned to them, administrators moderate everything and manage
+// roles.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{RoleUser: 0, RoleModerator: 1, RoleAdmin: 2}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
 // User represents a forum user
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// HasRole reports whether the user has the given role or a more privileged one
+func (u *User) HasRole(role string) bool {
+	rank, ok := roleRanks[role]
+	return ok && roleRanks[u.Role] >= rank
 }
 
 // UserAuth contains user authentication information
@@ -27,28 +53,86 @@ type UserRegistration struct {
 
 // UserLogin is used for login requests
 type UserLogin struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	RememberMe bool   `json:"remember_me"` // longer-lived session with a persistent cookie
 }
 
-// Session represents a user session
+// Session represents a user session. ID is the public handle of the session.
+// SessionID is the secret token stored in the cookie; it is only known right
+// after the session is created, the database keeps just its digest.
 type Session struct {
+	ID         string    `json:"id"`
 	UserID     string    `json:"user_id"`
-	SessionID  string    `json:"session_id"`
+	SessionID  string    `json:"session_id,omitempty"`
+	TokenHash  string    `json:"-"`
 	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	RememberMe bool      `json:"remember_me"`
 	CreatedAt  time.Time `json:"created_at"`
-	ExpiresAt  time.Time `json:"expires_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // idle expiry, pushed back while the session is used
+	// AbsoluteExpiresAt is the hard limit on the session's lifetime
+	AbsoluteExpiresAt time.Time `json:"absolute_expires_at"`
+}
+
+// PasswordChange is used to change the password of the logged-in user
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// PasswordReset sets a new password using a token from a reset mail
+type PasswordReset struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
 }
 
 // LoginResponse is the response after successful login
 type LoginResponse struct {
 	User      User   `json:"user"`
-	SessionID string `json:"session_id"`
+	CSRFToken string `json:"csrf_token"`
 }
 
+// VerifyResponse is the current user as returned by session verification,
+// along with the categories they moderate, any suspension that makes the
+// account read-only and the CSRF token to send on state-changing requests
+type VerifyResponse struct {
+	User
+	ModeratedCategories []int  `json:"moderated_categories,omitempty"`
+	Suspension          *Ban   `json:"suspension,omitempty"`
+	CSRFToken           string `json:"csrf_token"`
+}
 
+// The LogValue methods keep passwords, hashes and session tokens out of the
+// logs when one of these values is logged as a whole.
+
+func (a UserAuth) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("user_id", a.UserID))
+}
 
+func (u UserRegistration) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("username", u.Username), slog.String("email", u.Email))
+}
 
+func (l UserLogin) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("email", l.Email), slog.Bool("remember_me", l.RememberMe))
+}
 
+func (s Session) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", s.ID),
+		slog.String("user_id", s.UserID),
+		slog.String("ip_address", s.IPAddress),
+		slog.Bool("remember_me", s.RememberMe),
+		slog.Time("expires_at", s.ExpiresAt),
+	)
+}
 
+func (PasswordChange) LogValue() slog.Value {
+	return slog.GroupValue()
+}
 
+func (PasswordReset) LogValue() slog.Value {
+	return slog.GroupValue()
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// AuditRepository appends to and reads the audit log. Entries are never
+// changed or removed; triggers on the table enforce it.
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Append adds an event to the audit log. Times are stored in UTC so that
+// the since and until filters can compare created_at as text.
+func (r *AuditRepository) Append(event models.AuditEvent) error {
+	// Anonymous events have no actor
+	var actorID interface{}
+	if event.ActorID != "" {
+		actorID = event.ActorID
+	}
+
+	_, err := r.db.Exec(`INSERT INTO audit_log (event, actor_id, target_type, target_id, ip_address, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		event.Event, actorID, event.TargetType, event.TargetID, event.IPAddress, event.Details, time.Now().UTC())
+	return err
+}
+
+// List returns one page of the audit log matching the filter, newest first
+func (r *AuditRepository) List(filter models.AuditFilter, page models.Page) ([]models.AuditEvent, *models.Cursor, error) {
+	var conditions []string
+	var args []interface{}
+	for _, f := range []struct{ column, value string }{
+		{"event", filter.Event},
+		{"actor_id", filter.ActorID},
+		{"target_type", filter.TargetType},
+		{"target_id", filter.TargetID},
+		{"ip_address", filter.IPAddress},
+	} {
+		if f.value != "" {
+			conditions = append(conditions, f.column+" = ?")
+			args = append(args, f.value)
+		}
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+	if page.After != nil {
+		condition, idArgs, err := idCondition("event_id", page.After)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, idArgs...)
+	}
+
+	query := `SELECT event_id, event, COALESCE(actor_id, ''), target_type, target_id, ip_address, details, created_at, CAST(created_at AS TEXT)
+			  FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY event_id DESC LIMIT ?"
+	args = append(args, pageLimit(page))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	var next *models.Cursor
+	var lastCreatedAt string
+	for rows.Next() {
+		if len(events) == page.Limit {
+			// The extra row only proves there is another page
+			next = &models.Cursor{CreatedAt: lastCreatedAt, ID: strconv.FormatInt(events[len(events)-1].ID, 10)}
+			break
+		}
+		var e models.AuditEvent
+		if err := rows.Scan(&e.ID, &e.Event, &e.ActorID, &e.TargetType, &e.TargetID, &e.IPAddress, &e.Details, &e.CreatedAt, &lastCreatedAt); err != nil {
+			return nil, nil, err
+		}
+		events = append(events, e)
+	}
+	return events, next, rows.Err()
+}
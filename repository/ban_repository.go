@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"forum/models"
+	"forum/utils"
+)
+
+var (
+	ErrBanNotFound = errors.New("ban not found")
+	ErrNotBanned   = errors.New("user has no active ban or suspension")
+)
+
+const banColumns = "ban_id, user_id, kind, reason, issued_by, created_at, expires_at, lifted_at, lifted_by"
+
+// activeBanCondition matches restrictions that are neither lifted nor expired
+const activeBanCondition = "lifted_at IS NULL AND (expires_at IS NULL OR julianday(expires_at) > julianday('now'))"
+
+// BanRepository stores bans and suspensions
+type BanRepository struct {
+	db *sql.DB
+}
+
+// NewBanRepository creates a new BanRepository
+func NewBanRepository(db *sql.DB) *BanRepository {
+	return &BanRepository{db: db}
+}
+
+// Create records a new ban or suspension
+func (r *BanRepository) Create(ban models.Ban) (*models.Ban, error) {
+	ban.ID = utils.GenerateUUID()
+	ban.CreatedAt = time.Now()
+	_, err := r.db.Exec(`INSERT INTO user_bans (ban_id, user_id, kind, reason, issued_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		ban.ID, ban.UserID, ban.Kind, ban.Reason, ban.IssuedBy, ban.CreatedAt, ban.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+func scanBan(row interface{ Scan(...interface{}) error }) (*models.Ban, error) {
+	var b models.Ban
+	err := row.Scan(&b.ID, &b.UserID, &b.Kind, &b.Reason, &b.IssuedBy, &b.CreatedAt, &b.ExpiresAt, &b.LiftedAt, &b.LiftedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetActive returns the restriction currently in force for the user, or
+// ErrNotBanned. When several apply, a ban wins over a suspension and the
+// longest-lasting one is returned.
+func (r *BanRepository) GetActive(userID string) (*models.Ban, error) {
+	ban, err := scanBan(r.db.QueryRow(
+		"SELECT "+banColumns+" FROM user_bans WHERE user_id = ? AND "+activeBanCondition+`
+		ORDER BY kind = 'ban' DESC, expires_at IS NULL DESC, julianday(expires_at) DESC LIMIT 1`,
+		userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotBanned
+	}
+	return ban, err
+}
+
+// ListByUser returns every ban and suspension of the user, newest first
+func (r *BanRepository) ListByUser(userID string) ([]models.Ban, error) {
+	rows, err := r.db.Query("SELECT "+banColumns+" FROM user_bans WHERE user_id = ? ORDER BY julianday(created_at) DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bans := []models.Ban{}
+	for rows.Next() {
+		ban, err := scanBan(rows)
+		if err != nil {
+			return nil, err
+		}
+		bans = append(bans, *ban)
+	}
+	return bans, rows.Err()
+}
+
+// Lift ends an active ban or suspension early
+func (r *BanRepository) Lift(banID, liftedBy string) error {
+	res, err := r.db.Exec("UPDATE user_bans SET lifted_at = ?, lifted_by = ? WHERE ban_id = ? AND "+activeBanCondition,
+		time.Now(), liftedBy, banID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrBanNotFound
+	}
+	return nil
+}
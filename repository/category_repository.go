@@ -3,9 +3,15 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
 	"forum/models"
 )
 
+var ErrModeratorNotFound = errors.New("user does not moderate this category")
+
 type CategoryRepository struct {
 	db *sql.DB
 }
@@ -32,93 +38,151 @@ func (r *CategoryRepository) GetAll() ([]models.Category, error) {
 	return categories, nil
 }
 
-// repository/post_repository.go
-func (r *PostRepository) GetPostsByCategoryWithUser(categoryID int) ([]models.PostWithUser, error) {
-	query := `SELECT p.post_id, p.user_id, u.username, p.category_id, p.title, p.content, p.created_at
-			  FROM posts p JOIN user u ON p.user_id = u.user_id
-			  WHERE p.category_id = ?`
+// GetByIDs returns the categories matching the given IDs; unknown IDs are skipped
+func (r *CategoryRepository) GetByIDs(ids []int) ([]models.Category, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
 
-	rows, err := r.db.Query(query, categoryID)
+	placeholders := strings.Repeat("?,", len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := r.db.Query("SELECT category_id, name FROM categories WHERE category_id IN ("+placeholders[:len(placeholders)-1]+")", args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var posts []models.PostWithUser
+	var categories []models.Category
 	for rows.Next() {
-		var p models.PostWithUser
-		if err := rows.Scan(&p.ID, &p.UserID, &p.Username, &p.CategoryID, &p.Title, &p.Content, &p.CreatedAt); err != nil {
+		var cat models.Category
+		if err := rows.Scan(&cat.ID, &cat.Name); err != nil {
 			return nil, err
 		}
-		posts = append(posts, p)
+		categories = append(categories, cat)
 	}
-	return posts, nil
+	return categories, rows.Err()
 }
 
 // repository/comment_repository.go
-func (r *CommentRepository) GetCommentsByPostWithUser(postID string) ([]models.CommentWithUser, error) {
-	query := `SELECT c.comment_id, c.post_id, c.user_id, u.username, c.content, c.created_at
+// GetCommentsByPostWithUser returns one page of a post's top-level comments, oldest first.
+// With includeDeleted, deleted comments and the comments of deleted posts are included as placeholders.
+func (r *CommentRepository) GetCommentsByPostWithUser(postID string, page models.Page, includeDeleted bool) ([]models.CommentWithUser, *models.Cursor, error) {
+	query := `SELECT c.comment_id, c.post_id, c.parent_comment_id, c.depth, c.user_id, u.username, ` + commentContentColumn(includeDeleted) + `,
+			  c.created_at, c.updated_at, c.deleted_at IS NOT NULL, CAST(c.created_at AS TEXT)
 			  FROM comments c JOIN user u ON c.user_id = u.user_id
-			  WHERE c.post_id = ?`
+			  JOIN posts p ON p.post_id = c.post_id AND p.hidden_at IS NULL AND (? OR p.deleted_at IS NULL)
+			  WHERE c.post_id = ? AND c.parent_comment_id IS NULL AND ` + commentVisibleCondition(includeDeleted)
+	args := []interface{}{includeDeleted, postID}
+	if page.After != nil {
+		condition, keysetArgs := keysetCondition("c.created_at", "c.comment_id", page.After, false)
+		query += " AND " + condition
+		args = append(args, keysetArgs...)
+	}
+	query += " ORDER BY c.created_at ASC, c.comment_id ASC LIMIT ?"
+	args = append(args, pageLimit(page))
 
-	rows, err := r.db.Query(query, postID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
 	var comments []models.CommentWithUser
+	var next *models.Cursor
+	var lastCreatedAt string
 	for rows.Next() {
+		if len(comments) == page.Limit {
+			next = &models.Cursor{CreatedAt: lastCreatedAt, ID: comments[len(comments)-1].ID}
+			break
+		}
 		var c models.CommentWithUser
-		if err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Username, &c.Content, &c.CreatedAt); err != nil {
-			return nil, err
+		if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.Depth, &c.UserID, &c.Username, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.Deleted, &lastCreatedAt); err != nil {
+			return nil, nil, err
 		}
 		comments = append(comments, c)
 	}
-	return comments, nil
+	return comments, next, rows.Err()
 }
 
-// repository/reaction_repository.go
-func (r *ReactionRepository) GetReactionsByPostWithUser(postID string) ([]models.ReactionWithUser, error) {
-	query := `SELECT r.user_id, u.username, r.reaction_type, r.post_id, r.created_at
-			  FROM reactions r JOIN user u ON r.user_id = u.user_id
-			  WHERE r.post_id = ?`
+// AddModerator assigns a user to moderate a category. Assigning twice is not an error.
+func (r *CategoryRepository) AddModerator(categoryID int, userID string) error {
+	_, err := r.db.Exec(
+		"INSERT OR IGNORE INTO category_moderators (category_id, user_id, created_at) VALUES (?, ?, ?)",
+		categoryID, userID, time.Now(),
+	)
+	return err
+}
 
-	rows, err := r.db.Query(query, postID)
+// RemoveModerator ends a user's assignment to a category
+func (r *CategoryRepository) RemoveModerator(categoryID int, userID string) error {
+	res, err := r.db.Exec("DELETE FROM category_moderators WHERE category_id = ? AND user_id = ?", categoryID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrModeratorNotFound
+	}
+	return nil
+}
+
+// ListModerators returns the moderators of a category in order of assignment
+func (r *CategoryRepository) ListModerators(categoryID int) ([]models.CategoryModerator, error) {
+	rows, err := r.db.Query(`SELECT m.category_id, m.user_id, u.username, m.created_at
+			  FROM category_moderators m JOIN user u ON m.user_id = u.user_id
+			  WHERE m.category_id = ? ORDER BY m.created_at`, categoryID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var reactions []models.ReactionWithUser
+	moderators := []models.CategoryModerator{}
 	for rows.Next() {
-		var r models.ReactionWithUser
-		if err := rows.Scan(&r.UserID, &r.Username, &r.ReactionType, &r.PostID, &r.CreatedAt); err != nil {
+		var m models.CategoryModerator
+		if err := rows.Scan(&m.CategoryID, &m.UserID, &m.Username, &m.CreatedAt); err != nil {
 			return nil, err
 		}
-		reactions = append(reactions, r)
+		moderators = append(moderators, m)
 	}
-	return reactions, nil
+	return moderators, rows.Err()
 }
 
-func (r *ReactionRepository) GetReactionsByCommentWithUser(commentID string) ([]models.ReactionWithUser, error) {
-	query := `SELECT r.user_id, u.username, r.reaction_type, r.comment_id, r.created_at
-			  FROM reactions r JOIN user u ON r.user_id = u.user_id
-			  WHERE r.comment_id = ?`
-
-	rows, err := r.db.Query(query, commentID)
+// ModeratedCategoryIDs returns the categories the user is assigned to moderate
+func (r *CategoryRepository) ModeratedCategoryIDs(userID string) ([]int, error) {
+	rows, err := r.db.Query("SELECT category_id FROM category_moderators WHERE user_id = ? ORDER BY category_id", userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var reactions []models.ReactionWithUser
+	var ids []int
 	for rows.Next() {
-		var r models.ReactionWithUser
-		if err := rows.Scan(&r.UserID, &r.Username, &r.ReactionType, &r.CommentID, &r.CreatedAt); err != nil {
+		var id int
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		reactions = append(reactions, r)
+		ids = append(ids, id)
 	}
-	return reactions, nil
+	return ids, rows.Err()
+}
+
+// IsModerator reports whether the user moderates at least one of the categories
+func (r *CategoryRepository) IsModerator(userID string, categoryIDs []int) (bool, error) {
+	if len(categoryIDs) == 0 {
+		return false, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(categoryIDs))
+	args := []interface{}{userID}
+	for _, id := range categoryIDs {
+		args = append(args, id)
+	}
+
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM category_moderators WHERE user_id = ? AND category_id IN ("+
+		placeholders[:len(placeholders)-1]+"))", args...).Scan(&exists)
+	return exists, err
 }
@@ -2,12 +2,15 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"time"
 
 	"forum/models"
 	"forum/utils"
 )
 
+var ErrCommentNotFound = errors.New("comment not found")
+
 type CommentRepository struct {
 	db *sql.DB
 }
@@ -16,36 +19,171 @@ func NewCommentRepository(db *sql.DB) *CommentRepository {
 	return &CommentRepository{db: db}
 }
 
-func (r *CommentRepository) GetAllComments() ([]models.Comment, error) {
-	rows, err := r.db.Query(`
-		SELECT comment_id, post_id, user_id, content, created_at, updated_at 
-		FROM comments ORDER BY created_at ASC`)
+// Create inserts a new comment into the database
+func (r *CommentRepository) Create(comment models.Comment) (*models.Comment, error) {
+	comment.ID = utils.GenerateUUID()
+	comment.CreatedAt = sortableNow()
+	_, err := r.db.Exec(`INSERT INTO comments (comment_id, post_id, parent_comment_id, depth, user_id, content, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		comment.ID, comment.PostID, comment.ParentID, comment.Depth, comment.UserID, comment.Content, sortableTime(comment.CreatedAt))
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return &comment, nil
+}
 
-	var comments []models.Comment
-	for rows.Next() {
-		var c models.Comment
-		err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Content, &c.CreatedAt, &c.UpdatedAt)
-		if err != nil {
-			return nil, err
+// GetByID retrieves a single comment by its ID. Deleted comments are not found.
+func (r *CommentRepository) GetByID(id string) (*models.Comment, error) {
+	var c models.Comment
+	err := r.db.QueryRow(`
+		SELECT comment_id, post_id, parent_comment_id, depth, user_id, content, created_at, updated_at, hidden_at IS NOT NULL
+		FROM comments WHERE comment_id = ? AND deleted_at IS NULL`, id).
+		Scan(&c.ID, &c.PostID, &c.ParentID, &c.Depth, &c.UserID, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.Hidden)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCommentNotFound
 		}
-		comments = append(comments, c)
+		return nil, err
 	}
-
-	return comments, nil
+	return &c, nil
 }
 
-// Create inserts a new comment into the database
-func (r *CommentRepository) Create(comment models.Comment) (*models.Comment, error) {
-	comment.ID = utils.GenerateUUID()
-	comment.CreatedAt = time.Now()
-	_, err := r.db.Exec(`INSERT INTO comments (comment_id, post_id, user_id, content, created_at) VALUES (?, ?, ?, ?, ?)`,
-		comment.ID, comment.PostID, comment.UserID, comment.Content, comment.CreatedAt)
+// Update changes the content of a comment and stamps updated_at
+func (r *CommentRepository) Update(comment models.Comment) (*models.Comment, error) {
+	now := time.Now()
+	res, err := r.db.Exec(`UPDATE comments SET content = ?, updated_at = ? WHERE comment_id = ? AND deleted_at IS NULL`,
+		comment.Content, now, comment.ID)
 	if err != nil {
 		return nil, err
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrCommentNotFound
+	}
+	comment.UpdatedAt = &now
 	return &comment, nil
 }
+
+// DeletedCommentContent replaces the text of deleted comments that still have
+// replies, keeping the thread below them readable
+const DeletedCommentContent = "[deleted]"
+
+// RemovedContent replaces the title and content of deleted posts and comments
+// shown to moderators, who can restore them from the trash
+const RemovedContent = "[removed]"
+
+// HiddenCommentContent is shown instead of comments hidden by a moderator. They
+// stay in the thread so that replies to them keep their place.
+const HiddenCommentContent = "[hidden by a moderator]"
+
+// commentContentColumn selects the content of comment c as the viewer may see
+// it; moderators (includeDeleted) get a different placeholder for deleted comments
+func commentContentColumn(includeDeleted bool) string {
+	deleted := DeletedCommentContent
+	if includeDeleted {
+		deleted = RemovedContent
+	}
+	return `CASE WHEN c.deleted_at IS NOT NULL THEN '` + deleted + `'
+		WHEN c.hidden_at IS NOT NULL THEN '` + HiddenCommentContent + `'
+		ELSE c.content END`
+}
+
+// commentVisibleCondition leaves out deleted comments without replies, unless
+// includeDeleted is set. Deleted comments with replies stay as placeholders.
+func commentVisibleCondition(includeDeleted bool) string {
+	if includeDeleted {
+		return "1"
+	}
+	return `(c.deleted_at IS NULL OR EXISTS (SELECT 1 FROM comments reply WHERE reply.parent_comment_id = c.comment_id))`
+}
+
+// Delete moves a comment to the trash, recording who deleted it. Its reactions
+// and replies are kept, so restoring the comment brings it back as it was.
+func (r *CommentRepository) Delete(id, deletedBy string) error {
+	res, err := r.db.Exec(`UPDATE comments SET deleted_at = ?, deleted_by = ? WHERE comment_id = ? AND deleted_at IS NULL`,
+		time.Now(), deletedBy, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// GetFirstPagePerPost returns the oldest top-level comments of many posts in one query per
+// batch, keyed by post ID, along with each post's next-page cursor
+func (r *CommentRepository) GetFirstPagePerPost(postIDs []string, limit int, includeDeleted bool) (map[string][]models.CommentWithUser, map[string]*models.Cursor, error) {
+	comments := make(map[string][]models.CommentWithUser, len(postIDs))
+	next := make(map[string]*models.Cursor)
+	lastCreatedAt := make(map[string]string)
+
+	err := inBatches(postIDs, func(placeholders string, args []interface{}) error {
+		rows, err := r.db.Query(`
+			SELECT comment_id, post_id, parent_comment_id, depth, user_id, username, content, created_at, updated_at, deleted, raw_created_at
+			FROM (
+				SELECT c.comment_id, c.post_id, c.parent_comment_id, c.depth, c.user_id, u.username, `+commentContentColumn(includeDeleted)+` AS content,
+				       c.created_at, c.updated_at, c.deleted_at IS NOT NULL AS deleted, CAST(c.created_at AS TEXT) AS raw_created_at,
+				       ROW_NUMBER() OVER (PARTITION BY c.post_id ORDER BY c.created_at ASC, c.comment_id ASC) AS rn
+				FROM comments c JOIN user u ON c.user_id = u.user_id
+				WHERE c.post_id IN (`+placeholders+`) AND c.parent_comment_id IS NULL AND `+commentVisibleCondition(includeDeleted)+`
+			)
+			WHERE rn <= ?
+			ORDER BY post_id, rn`, append(args, limit+1)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c models.CommentWithUser
+			var rawCreatedAt string
+			if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.Depth, &c.UserID, &c.Username, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.Deleted, &rawCreatedAt); err != nil {
+				return err
+			}
+			page := comments[c.PostID]
+			if len(page) == limit {
+				next[c.PostID] = &models.Cursor{CreatedAt: lastCreatedAt[c.PostID], ID: page[len(page)-1].ID}
+				continue
+			}
+			comments[c.PostID] = append(page, c)
+			lastCreatedAt[c.PostID] = rawCreatedAt
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return comments, next, nil
+}
+
+// GetRepliesForComments returns every reply below the given comments, at any
+// depth, oldest first. Replies are loaded with one recursive query per batch.
+func (r *CommentRepository) GetRepliesForComments(commentIDs []string, includeDeleted bool) ([]models.CommentWithUser, error) {
+	var replies []models.CommentWithUser
+	err := inBatches(commentIDs, func(placeholders string, args []interface{}) error {
+		rows, err := r.db.Query(`
+			WITH RECURSIVE thread(comment_id) AS (
+				SELECT comment_id FROM comments WHERE parent_comment_id IN (`+placeholders+`)
+				UNION ALL
+				SELECT c.comment_id FROM comments c JOIN thread t ON c.parent_comment_id = t.comment_id
+			)
+			SELECT c.comment_id, c.post_id, c.parent_comment_id, c.depth, c.user_id, u.username, `+commentContentColumn(includeDeleted)+`,
+			       c.created_at, c.updated_at, c.deleted_at IS NOT NULL
+			FROM comments c JOIN user u ON c.user_id = u.user_id
+			WHERE c.comment_id IN (SELECT comment_id FROM thread) AND `+commentVisibleCondition(includeDeleted)+`
+			ORDER BY c.created_at ASC, c.comment_id ASC`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c models.CommentWithUser
+			if err := rows.Scan(&c.ID, &c.PostID, &c.ParentID, &c.Depth, &c.UserID, &c.Username, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.Deleted); err != nil {
+				return err
+			}
+			replies = append(replies, c)
+		}
+		return rows.Err()
+	})
+	return replies, err
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// LockoutRepository stores login lockout events
+type LockoutRepository struct {
+	DB *sql.DB
+}
+
+// NewLockoutRepository creates a new LockoutRepository
+func NewLockoutRepository(db *sql.DB) *LockoutRepository {
+	return &LockoutRepository{DB: db}
+}
+
+// Record stores a lockout event
+func (r *LockoutRepository) Record(lockout models.LoginLockout) error {
+	_, err := r.DB.Exec(
+		`INSERT INTO login_lockouts (scope, identifier, ip_address, failed_attempts, locked_until, created_at)
+         VALUES (?, ?, ?, ?, ?, ?)`,
+		lockout.Scope, lockout.Identifier, lockout.IPAddress, lockout.FailedAttempts, lockout.LockedUntil, time.Now(),
+	)
+	return err
+}
+
+// List returns one page of lockouts matching the filter, newest first
+func (r *LockoutRepository) List(filter models.LockoutFilter, page models.Page) ([]models.LoginLockout, *models.Cursor, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Scope != "" {
+		conditions = append(conditions, "scope = ?")
+		args = append(args, filter.Scope)
+	}
+	if filter.Identifier != "" {
+		conditions = append(conditions, "identifier = ?")
+		args = append(args, filter.Identifier)
+	}
+	if page.After != nil {
+		condition, idArgs, err := idCondition("id", page.After)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, idArgs...)
+	}
+
+	query := `SELECT id, scope, identifier, COALESCE(ip_address, ''), failed_attempts, locked_until, created_at, CAST(created_at AS TEXT)
+			  FROM login_lockouts`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, pageLimit(page))
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	lockouts := []models.LoginLockout{}
+	var next *models.Cursor
+	var lastCreatedAt string
+	for rows.Next() {
+		if len(lockouts) == page.Limit {
+			// The extra row only proves there is another page
+			last := lockouts[len(lockouts)-1]
+			next = &models.Cursor{CreatedAt: lastCreatedAt, ID: strconv.FormatInt(last.ID, 10)}
+			break
+		}
+		var l models.LoginLockout
+		if err := rows.Scan(&l.ID, &l.Scope, &l.Identifier, &l.IPAddress, &l.FailedAttempts, &l.LockedUntil, &l.CreatedAt, &lastCreatedAt); err != nil {
+			return nil, nil, err
+		}
+		lockouts = append(lockouts, l)
+	}
+	return lockouts, next, rows.Err()
+}
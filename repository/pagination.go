@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// sortableTimeLayout is how the timestamps that listings are paged by are
+// stored: UTC with millisecond precision, so that their text order is their
+// time order. It matches strftime('%Y-%m-%dT%H:%M:%fZ') in SQL.
+const sortableTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// sortableNow returns the current time at the precision it is stored with
+func sortableNow() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// sortableTime formats a timestamp for a column that listings are paged by
+func sortableTime(t time.Time) string {
+	return t.UTC().Format(sortableTimeLayout)
+}
+
+// keysetCondition returns the WHERE fragment selecting rows after the cursor in
+// a listing ordered by (createdCol, idCol), descending or ascending
+func keysetCondition(createdCol, idCol string, after *models.Cursor, desc bool) (string, []interface{}) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	condition := "(" + createdCol + " " + op + " ? OR (" + createdCol + " = ? AND " + idCol + " " + op + " ?))"
+	return condition, []interface{}{after.CreatedAt, after.CreatedAt, after.ID}
+}
+
+// idCondition returns the WHERE fragment selecting rows after the cursor in a
+// listing ordered by an autoincrement ID, newest first. Such IDs grow with
+// created_at, so they order the rows on their own.
+func idCondition(idCol string, after *models.Cursor) (string, []interface{}, error) {
+	id, err := strconv.ParseInt(after.ID, 10, 64)
+	if err != nil {
+		return "", nil, models.ErrInvalidCursor
+	}
+	return idCol + " < ?", []interface{}{id}, nil
+}
+
+// pageLimit returns the LIMIT to query with: one extra row tells whether another page exists
+func pageLimit(page models.Page) int {
+	return page.Limit + 1
+}
+
+// maxBatchSize keeps IN (...) lists well below SQLite's bound parameter limit
+const maxBatchSize = 500
+
+// inBatches calls fn once per batch of IDs with a matching "?, ?, ..." placeholder list
+func inBatches(ids []string, fn func(placeholders string, args []interface{}) error) error {
+	for start := 0; start < len(ids); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+
+		if err := fn(placeholders, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
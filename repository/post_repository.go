@@ -2,12 +2,23 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
 	"forum/models"
 	"forum/utils"
 )
 
+var ErrPostNotFound = errors.New("post not found")
+
+// postTitleColumn and postContentColumn select the title and content of post p,
+// replaced by RemovedContent once the post is deleted
+const (
+	postTitleColumn   = `CASE WHEN p.deleted_at IS NULL THEN p.title ELSE '` + RemovedContent + `' END`
+	postContentColumn = `CASE WHEN p.deleted_at IS NULL THEN p.content ELSE '` + RemovedContent + `' END`
+)
+
 type PostRepository struct {
 	db *sql.DB
 }
@@ -16,36 +27,251 @@ func NewPostRepository(db *sql.DB) *PostRepository {
 	return &PostRepository{db: db}
 }
 
-func (r *PostRepository) GetAllPosts() ([]models.Post, error) {
-	rows, err := r.db.Query(`
-		SELECT post_id, user_id, category_id, title, content, created_at, updated_at 
-		FROM posts ORDER BY created_at DESC`)
+// Create inserts a new post into the database and links it to its categories.
+// The first entry of post.CategoryIDs becomes the post's primary category.
+func (r *PostRepository) Create(post models.Post) (*models.Post, error) {
+	if len(post.CategoryIDs) == 0 {
+		post.CategoryIDs = []int{post.CategoryID}
+	}
+	post.CategoryID = post.CategoryIDs[0]
+	post.ID = utils.GenerateUUID()
+	post.CreatedAt = sortableNow()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO posts (post_id, user_id, category_id, title, content, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		post.ID, post.UserID, post.CategoryID, post.Title, post.Content, sortableTime(post.CreatedAt))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, categoryID := range post.CategoryIDs {
+		_, err = tx.Exec(`INSERT OR IGNORE INTO post_categories (post_id, category_id) VALUES (?, ?)`, post.ID, categoryID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// GetCategoryIDs returns the IDs of every category a post is listed under
+func (r *PostRepository) GetCategoryIDs(postID string) ([]int, error) {
+	rows, err := r.db.Query(`SELECT category_id FROM post_categories WHERE post_id = ? ORDER BY category_id`, postID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var posts []models.Post
+	ids := []int{}
 	for rows.Next() {
-		var post models.Post
-		err := rows.Scan(&post.ID, &post.UserID, &post.CategoryID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt)
-		if err != nil {
+		var id int
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		posts = append(posts, post)
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetByID retrieves a single post by its ID. Deleted posts are not found.
+func (r *PostRepository) GetByID(id string) (*models.Post, error) {
+	var post models.Post
+	err := r.db.QueryRow(`
+		SELECT post_id, user_id, category_id, title, content, created_at, updated_at, hidden_at IS NOT NULL
+		FROM posts WHERE post_id = ? AND deleted_at IS NULL`, id).
+		Scan(&post.ID, &post.UserID, &post.CategoryID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, &post.Hidden)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPostNotFound
+		}
+		return nil, err
 	}
 
-	return posts, nil
+	post.CategoryIDs, err = r.GetCategoryIDs(id)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
 }
 
-// Create inserts a new post into the database
-func (r *PostRepository) Create(post models.Post) (*models.Post, error) {
-	post.ID = utils.GenerateUUID()
-	post.CreatedAt = time.Now()
-	_, err := r.db.Exec(`INSERT INTO posts (post_id, user_id, category_id, title, content, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
-		post.ID, post.UserID, post.CategoryID, post.Title, post.Content, post.CreatedAt)
+// GetByIDWithUser retrieves a single post along with its author's username.
+// A deleted post is only found, as a placeholder, when includeDeleted is set.
+func (r *PostRepository) GetByIDWithUser(id string, includeDeleted bool) (*models.PostWithUser, error) {
+	var p models.PostWithUser
+	err := r.db.QueryRow(`
+		SELECT p.post_id, p.user_id, u.username, p.category_id, `+postTitleColumn+`, `+postContentColumn+`,
+		p.created_at, p.updated_at, p.deleted_at IS NOT NULL
+		FROM posts p JOIN user u ON p.user_id = u.user_id
+		WHERE p.post_id = ? AND p.hidden_at IS NULL AND (? OR p.deleted_at IS NULL)`, id, includeDeleted).
+		Scan(&p.ID, &p.UserID, &p.Username, &p.CategoryID, &p.Title, &p.Content, &p.CreatedAt, &p.UpdatedAt, &p.Deleted)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPostNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Update changes the title and content of a post and stamps updated_at
+func (r *PostRepository) Update(post models.Post) (*models.Post, error) {
+	now := time.Now()
+	res, err := r.db.Exec(`UPDATE posts SET title = ?, content = ?, updated_at = ? WHERE post_id = ? AND deleted_at IS NULL`,
+		post.Title, post.Content, now, post.ID)
 	if err != nil {
 		return nil, err
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrPostNotFound
+	}
+	post.UpdatedAt = &now
 	return &post, nil
 }
+
+// Delete moves a post to the trash, recording who deleted it. Its comments and
+// reactions are kept, so restoring the post brings them back.
+func (r *PostRepository) Delete(id, deletedBy string) error {
+	res, err := r.db.Exec(`UPDATE posts SET deleted_at = ?, deleted_by = ? WHERE post_id = ? AND deleted_at IS NULL`,
+		time.Now(), deletedBy, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// ListPosts returns one page of posts matching the filter, newest first, and
+// the cursor of the next page (nil on the last page)
+func (r *PostRepository) ListPosts(filter models.PostFilter, page models.Page) ([]models.PostWithUser, *models.Cursor, error) {
+	// Posts hidden by a moderator are never listed, deleted ones only for moderators
+	conditions := []string{`p.hidden_at IS NULL`}
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, `p.deleted_at IS NULL`)
+	}
+	var args []interface{}
+
+	if filter.CategoryID != 0 {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM post_categories pc WHERE pc.post_id = p.post_id AND pc.category_id = ?)`)
+		args = append(args, filter.CategoryID)
+	}
+	if filter.AuthorID != "" {
+		conditions = append(conditions, `p.user_id = ?`)
+		args = append(args, filter.AuthorID)
+	}
+	if filter.LikedBy != "" {
+		// Served by idx_reactions_user_id / idx_reactions_post_id
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM reactions r WHERE r.post_id = p.post_id AND r.user_id = ? AND r.reaction_type IN (?, ?))`)
+		args = append(args, filter.LikedBy, models.ReactionLike, models.ReactionLove)
+	}
+	if page.After != nil {
+		condition, keysetArgs := keysetCondition("p.created_at", "p.post_id", page.After, true)
+		conditions = append(conditions, condition)
+		args = append(args, keysetArgs...)
+	}
+
+	query := `SELECT p.post_id, p.user_id, u.username, p.category_id, ` + postTitleColumn + `, ` + postContentColumn + `,
+			  p.created_at, p.updated_at, p.deleted_at IS NOT NULL, CAST(p.created_at AS TEXT)
+			  FROM posts p JOIN user u ON p.user_id = u.user_id`
+	query += " WHERE " + strings.Join(conditions, " AND ")
+	query += " ORDER BY p.created_at DESC, p.post_id DESC LIMIT ?"
+	args = append(args, pageLimit(page))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.PostWithUser
+	var next *models.Cursor
+	var lastCreatedAt string
+	for rows.Next() {
+		if len(posts) == page.Limit {
+			// The extra row only proves there is another page
+			next = &models.Cursor{CreatedAt: lastCreatedAt, ID: posts[len(posts)-1].ID}
+			break
+		}
+		var p models.PostWithUser
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Username, &p.CategoryID, &p.Title, &p.Content, &p.CreatedAt, &p.UpdatedAt, &p.Deleted, &lastCreatedAt); err != nil {
+			return nil, nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, next, rows.Err()
+}
+
+// GetFirstPagePerCategory returns the newest posts of every category in a single
+// query, keyed by category ID, along with each category's next-page cursor
+func (r *PostRepository) GetFirstPagePerCategory(limit int) (map[int][]models.PostWithUser, map[int]*models.Cursor, error) {
+	rows, err := r.db.Query(`
+		SELECT category_id, post_id, user_id, username, primary_category_id, title, content, created_at, updated_at, raw_created_at
+		FROM (
+			SELECT pc.category_id, p.post_id, p.user_id, u.username, p.category_id AS primary_category_id,
+			       p.title, p.content, p.created_at, p.updated_at, CAST(p.created_at AS TEXT) AS raw_created_at,
+			       ROW_NUMBER() OVER (PARTITION BY pc.category_id ORDER BY p.created_at DESC, p.post_id DESC) AS rn
+			FROM post_categories pc
+			JOIN posts p ON pc.post_id = p.post_id
+			JOIN user u ON p.user_id = u.user_id
+			WHERE p.hidden_at IS NULL AND p.deleted_at IS NULL
+		)
+		WHERE rn <= ?
+		ORDER BY category_id, rn`, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	posts := make(map[int][]models.PostWithUser)
+	next := make(map[int]*models.Cursor)
+	lastCreatedAt := make(map[int]string)
+	for rows.Next() {
+		var categoryID int
+		var p models.PostWithUser
+		var rawCreatedAt string
+		if err := rows.Scan(&categoryID, &p.ID, &p.UserID, &p.Username, &p.CategoryID, &p.Title, &p.Content, &p.CreatedAt, &p.UpdatedAt, &rawCreatedAt); err != nil {
+			return nil, nil, err
+		}
+		page := posts[categoryID]
+		if len(page) == limit {
+			next[categoryID] = &models.Cursor{CreatedAt: lastCreatedAt[categoryID], ID: page[len(page)-1].ID}
+			continue
+		}
+		posts[categoryID] = append(page, p)
+		lastCreatedAt[categoryID] = rawCreatedAt
+	}
+	return posts, next, rows.Err()
+}
+
+// GetCategoryIDsForPosts returns the category IDs of many posts at once, keyed by post ID
+func (r *PostRepository) GetCategoryIDsForPosts(postIDs []string) (map[string][]int, error) {
+	result := make(map[string][]int, len(postIDs))
+	err := inBatches(postIDs, func(placeholders string, args []interface{}) error {
+		rows, err := r.db.Query(`SELECT post_id, category_id FROM post_categories
+			WHERE post_id IN (`+placeholders+`) ORDER BY post_id, category_id`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var postID string
+			var categoryID int
+			if err := rows.Scan(&postID, &categoryID); err != nil {
+				return err
+			}
+			result[postID] = append(result[postID], categoryID)
+		}
+		return rows.Err()
+	})
+	return result, err
+}
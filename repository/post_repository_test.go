@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"forum/config"
+	"forum/models"
+)
+
+// Rows written before timestamps were normalized mix the seed format, SQLite's
+// CURRENT_TIMESTAMP and Go's local time with its offset, across a DST change.
+// After the migration, paging must return every post once, in time order.
+func TestListPostsPagesAcrossMixedTimestamps(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`INSERT INTO user (user_id, username, email) VALUES ('pager', 'pager', 'pager@example.com')`); err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+
+	cest := time.FixedZone("CEST", 2*60*60)
+	for _, row := range []struct {
+		id        string
+		createdAt interface{}
+	}{
+		{"seed-format", "2025-03-30T00:50:00"},                           // 00:50 UTC
+		{"go-cet", "2025-03-30 01:55:00.5+01:00"},                        // 00:55:00.5 UTC
+		{"current-timestamp", "2025-03-30 01:00:00"},                     // 01:00 UTC
+		{"go-cest", time.Date(2025, 3, 30, 3, 5, 0, 0, cest)},            // 01:05 UTC
+		{"go-utc", time.Date(2025, 3, 30, 1, 2, 0, 123456789, time.UTC)}, // 01:02:00.123 UTC
+	} {
+		if _, err := db.Exec(`INSERT INTO posts (post_id, user_id, category_id, title, content, created_at) VALUES (?, 'pager', 1, 'Title', 'Content', ?)`,
+			row.id, row.createdAt); err != nil {
+			t.Fatalf("seed post %s: %v", row.id, err)
+		}
+	}
+	if _, err := db.Exec(config.NormalizeTimestamps); err != nil {
+		t.Fatalf("normalize timestamps: %v", err)
+	}
+
+	repo := NewPostRepository(db)
+	created, err := repo.Create(models.Post{UserID: "pager", CategoryIDs: []int{1}, Title: "New", Content: "Content"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+
+	want := []string{created.ID, "go-cest", "go-utc", "current-timestamp", "go-cet", "seed-format"}
+	var got []string
+	page := models.Page{Limit: 2}
+	for i := 0; i < len(want); i++ {
+		posts, next, err := repo.ListPosts(models.PostFilter{AuthorID: "pager"}, page)
+		if err != nil {
+			t.Fatalf("list posts: %v", err)
+		}
+		for _, p := range posts {
+			got = append(got, p.ID)
+		}
+		if next == nil {
+			break
+		}
+		page.After = next
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("paged through %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("paged through %v, want %v", got, want)
+		}
+	}
+}
@@ -2,9 +2,17 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"time"
+
 	"forum/models"
 )
 
+var (
+	ErrInvalidReactionType   = errors.New("invalid reaction type")
+	ErrInvalidReactionTarget = errors.New("invalid reaction target")
+)
+
 type ReactionRepository struct {
 	db *sql.DB
 }
@@ -13,24 +21,132 @@ func NewReactionRepository(db *sql.DB) *ReactionRepository {
 	return &ReactionRepository{db: db}
 }
 
-func (r *ReactionRepository) GetAllReactions() ([]models.Reaction, error) {
-	rows, err := r.db.Query(`
-		SELECT user_id, reaction_type, comment_id, post_id, created_at 
-		FROM reactions`)
+// targetColumn maps a reaction target type to its column in the reactions table
+func targetColumn(targetType string) (string, error) {
+	switch targetType {
+	case models.TargetPost:
+		return "post_id", nil
+	case models.TargetComment:
+		return "comment_id", nil
+	}
+	return "", ErrInvalidReactionTarget
+}
+
+// Toggle creates, switches or removes the user's reaction on a post or comment.
+// Reacting again with the same type removes the reaction, reacting with a
+// different type replaces it. It returns the user's reaction type afterwards,
+// or nil if the reaction was removed.
+func (r *ReactionRepository) Toggle(userID, targetType, targetID string, reactionType int) (*int, error) {
+	column, err := targetColumn(targetType)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsValidReactionType(reactionType) {
+		return nil, ErrInvalidReactionType
+	}
+
+	tx, err := r.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	// The unique index on (user_id, target) makes the insert and the switch one
+	// statement; a reaction of the same type is left alone and removed below
+	result, err := tx.Exec(`INSERT INTO reactions (user_id, reaction_type, `+column+`, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, `+column+`) WHERE `+column+` IS NOT NULL DO UPDATE
+		SET reaction_type = excluded.reaction_type, created_at = excluded.created_at
+		WHERE reaction_type <> excluded.reaction_type`,
+		userID, reactionType, targetID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	current := &reactionType
+	if changed == 0 {
+		if _, err := tx.Exec(`DELETE FROM reactions WHERE user_id = ? AND `+column+` = ?`, userID, targetID); err != nil {
+			return nil, err
+		}
+		current = nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// GetCounts returns the number of reactions of each type on a post or comment
+func (r *ReactionRepository) GetCounts(targetType, targetID string) (models.ReactionCounts, error) {
+	var counts models.ReactionCounts
+	column, err := targetColumn(targetType)
+	if err != nil {
+		return counts, err
+	}
+
+	rows, err := r.db.Query(`SELECT reaction_type, COUNT(*) FROM reactions WHERE `+column+` = ? GROUP BY reaction_type`, targetID)
+	if err != nil {
+		return counts, err
+	}
 	defer rows.Close()
 
-	var reactions []models.Reaction
 	for rows.Next() {
-		var react models.Reaction
-		err := rows.Scan(&react.UserID, &react.Type, &react.CommentID, &react.PostID, &react.CreatedAt)
-		if err != nil {
-			return nil, err
+		var reactionType, count int
+		if err := rows.Scan(&reactionType, &count); err != nil {
+			return counts, err
+		}
+		switch reactionType {
+		case models.ReactionLike:
+			counts.Like = count
+		case models.ReactionDislike:
+			counts.Dislike = count
+		case models.ReactionLove:
+			counts.Love = count
 		}
-		reactions = append(reactions, react)
 	}
+	return counts, rows.Err()
+}
 
-	return reactions, nil
+// GetReactionsForPosts returns the reactions on many posts at once, keyed by post ID
+func (r *ReactionRepository) GetReactionsForPosts(postIDs []string) (map[string][]models.ReactionWithUser, error) {
+	return r.getReactionsFor("post_id", postIDs)
+}
+
+// GetReactionsForComments returns the reactions on many comments at once, keyed by comment ID
+func (r *ReactionRepository) GetReactionsForComments(commentIDs []string) (map[string][]models.ReactionWithUser, error) {
+	return r.getReactionsFor("comment_id", commentIDs)
+}
+
+func (r *ReactionRepository) getReactionsFor(column string, ids []string) (map[string][]models.ReactionWithUser, error) {
+	result := make(map[string][]models.ReactionWithUser, len(ids))
+	err := inBatches(ids, func(placeholders string, args []interface{}) error {
+		rows, err := r.db.Query(`SELECT r.`+column+`, r.user_id, u.username, r.reaction_type, r.created_at
+			FROM reactions r JOIN user u ON r.user_id = u.user_id
+			WHERE r.`+column+` IN (`+placeholders+`)
+			ORDER BY r.created_at`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var targetID string
+			var reaction models.ReactionWithUser
+			if err := rows.Scan(&targetID, &reaction.UserID, &reaction.Username, &reaction.ReactionType, &reaction.CreatedAt); err != nil {
+				return err
+			}
+			if column == "post_id" {
+				reaction.PostID = &targetID
+			} else {
+				reaction.CommentID = &targetID
+			}
+			result[targetID] = append(result[targetID], reaction)
+		}
+		return rows.Err()
+	})
+	return result, err
 }
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"testing"
+
+	"forum/models"
+)
+
+// newTestDB creates a database with the full schema and the mock data in a
+// temporary directory
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	db, err := models.InitDB()
+	if err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// seedReactionTarget inserts a user and a post with one comment to react to
+func seedReactionTarget(t *testing.T, db *sql.DB) {
+	t.Helper()
+	for _, stmt := range []string{
+		`INSERT INTO user (user_id, username, email) VALUES ('reactor', 'reactor', 'reactor@example.com')`,
+		`INSERT INTO posts (post_id, user_id, category_id, title, content) VALUES ('react-post', 'reactor', 1, 'Title', 'Content')`,
+		`INSERT INTO comments (comment_id, post_id, user_id, content) VALUES ('react-comment', 'react-post', 'reactor', 'Comment')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+}
+
+func TestReactionToggle(t *testing.T) {
+	db := newTestDB(t)
+	seedReactionTarget(t, db)
+	repo := NewReactionRepository(db)
+
+	for _, target := range []struct{ targetType, targetID string }{
+		{models.TargetPost, "react-post"},
+		{models.TargetComment, "react-comment"},
+	} {
+		for i, step := range []struct {
+			reactionType int
+			want         *int
+			counts       models.ReactionCounts
+		}{
+			{models.ReactionLike, intPtr(models.ReactionLike), models.ReactionCounts{Like: 1}},
+			{models.ReactionDislike, intPtr(models.ReactionDislike), models.ReactionCounts{Dislike: 1}},
+			{models.ReactionDislike, nil, models.ReactionCounts{}},
+			{models.ReactionLove, intPtr(models.ReactionLove), models.ReactionCounts{Love: 1}},
+		} {
+			got, err := repo.Toggle("reactor", target.targetType, target.targetID, step.reactionType)
+			if err != nil {
+				t.Fatalf("%s step %d: %v", target.targetType, i+1, err)
+			}
+			if (got == nil) != (step.want == nil) || (got != nil && *got != *step.want) {
+				t.Errorf("%s step %d: got reaction %v, want %v", target.targetType, i+1, deref(got), deref(step.want))
+			}
+			counts, err := repo.GetCounts(target.targetType, target.targetID)
+			if err != nil {
+				t.Fatalf("%s step %d: counts: %v", target.targetType, i+1, err)
+			}
+			if counts != step.counts {
+				t.Errorf("%s step %d: counts %+v, want %+v", target.targetType, i+1, counts, step.counts)
+			}
+		}
+	}
+}
+
+func TestReactionsAreUniquePerUserAndTarget(t *testing.T) {
+	db := newTestDB(t)
+	seedReactionTarget(t, db)
+
+	for _, insert := range []string{
+		`INSERT INTO reactions (user_id, reaction_type, post_id) VALUES ('reactor', 1, 'react-post')`,
+		`INSERT INTO reactions (user_id, reaction_type, comment_id) VALUES ('reactor', 1, 'react-comment')`,
+	} {
+		if _, err := db.Exec(insert); err != nil {
+			t.Fatalf("first reaction: %v", err)
+		}
+		if _, err := db.Exec(insert); err == nil {
+			t.Errorf("a second reaction by the same user was accepted: %s", insert)
+		}
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func deref(n *int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
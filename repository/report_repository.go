@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"forum/models"
+	"forum/utils"
+)
+
+var (
+	ErrAlreadyReported = errors.New("content already reported by this user")
+	ErrNoOpenReports   = errors.New("no open reports for this content")
+)
+
+// ReportRepository stores reports and the moderation actions that resolve them
+type ReportRepository struct {
+	db *sql.DB
+}
+
+// NewReportRepository creates a new ReportRepository
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// Create files a report. A user's second report of the same content fails with ErrAlreadyReported.
+func (r *ReportRepository) Create(report models.Report) (*models.Report, error) {
+	report.ID = utils.GenerateUUID()
+	report.Status = models.ReportOpen
+	report.CreatedAt = sortableNow()
+
+	res, err := r.db.Exec(`INSERT INTO reports (report_id, reporter_id, target_type, target_id, reason, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (reporter_id, target_type, target_id) DO NOTHING`,
+		report.ID, report.ReporterID, report.TargetType, report.TargetID, report.Reason, report.Status, sortableTime(report.CreatedAt))
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrAlreadyReported
+	}
+	return &report, nil
+}
+
+// ListOpenTargets returns one page of reported posts and comments with their
+// open reports, most reported first, then longest waiting. When moderatorID is
+// set only content in the categories that user moderates is included. Content
+// that no longer exists or is in the trash is left out.
+func (r *ReportRepository) ListOpenTargets(moderatorID string, page models.Page) ([]models.ReportedTarget, *models.Cursor, error) {
+	query := `SELECT r.target_type, r.target_id, COALESCE(p.post_id, c.post_id), u.user_id, u.username,
+			  COALESCE(p.title, ''), COALESCE(p.content, c.content),
+			  COALESCE(p.hidden_at, c.hidden_at) IS NOT NULL, COUNT(*), CAST(MIN(r.created_at) AS TEXT)
+			  FROM reports r
+			  LEFT JOIN posts p ON r.target_type = 'post' AND p.post_id = r.target_id
+			  LEFT JOIN comments c ON r.target_type = 'comment' AND c.comment_id = r.target_id
+			  JOIN user u ON u.user_id = COALESCE(p.user_id, c.user_id)
+			  WHERE r.status = ? AND COALESCE(p.deleted_at, c.deleted_at) IS NULL`
+	args := []interface{}{models.ReportOpen}
+	if moderatorID != "" {
+		query += ` AND EXISTS (SELECT 1 FROM post_categories pc
+			  JOIN category_moderators m ON m.category_id = pc.category_id
+			  WHERE pc.post_id = COALESCE(p.post_id, c.post_id) AND m.user_id = ?)`
+		args = append(args, moderatorID)
+	}
+	query += ` GROUP BY r.target_type, r.target_id`
+	if page.After != nil {
+		// Keyset on the ORDER BY below: (count DESC, first report ASC, target ASC)
+		query += ` HAVING COUNT(*) < ? OR (COUNT(*) = ? AND (MIN(r.created_at) > ?
+			  OR (MIN(r.created_at) = ? AND r.target_type || ':' || r.target_id > ?)))`
+		args = append(args, page.After.Count, page.After.Count, page.After.CreatedAt, page.After.CreatedAt, page.After.ID)
+	}
+	query += ` ORDER BY COUNT(*) DESC, MIN(r.created_at) ASC, r.target_type || ':' || r.target_id ASC
+			  LIMIT ?`
+	args = append(args, pageLimit(page))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	targets := []models.ReportedTarget{}
+	var next *models.Cursor
+	var lastFirstReport string
+	for rows.Next() {
+		if len(targets) == page.Limit {
+			// The extra row only proves there is another page
+			last := targets[len(targets)-1]
+			next = &models.Cursor{CreatedAt: lastFirstReport, ID: last.TargetType + ":" + last.TargetID, Count: last.ReportCount}
+			break
+		}
+		var t models.ReportedTarget
+		if err := rows.Scan(&t.TargetType, &t.TargetID, &t.PostID, &t.AuthorID, &t.AuthorUsername,
+			&t.Title, &t.Content, &t.Hidden, &t.ReportCount, &lastFirstReport); err != nil {
+			return nil, nil, err
+		}
+		t.Reports = []models.Report{}
+		targets = append(targets, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := r.attachOpenReports(targets); err != nil {
+		return nil, nil, err
+	}
+	return targets, next, nil
+}
+
+// attachOpenReports loads the open reports of the given targets, oldest first
+func (r *ReportRepository) attachOpenReports(targets []models.ReportedTarget) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(targets))
+	ids := make([]string, len(targets))
+	for i, t := range targets {
+		index[t.TargetType+":"+t.TargetID] = i
+		ids[i] = t.TargetID
+	}
+
+	return inBatches(ids, func(placeholders string, args []interface{}) error {
+		rows, err := r.db.Query(`SELECT r.report_id, r.reporter_id, u.username, r.target_type, r.target_id, r.reason, r.status, r.created_at
+			FROM reports r JOIN user u ON u.user_id = r.reporter_id
+			WHERE r.status = ? AND r.target_id IN (`+placeholders+`)
+			ORDER BY r.created_at ASC, r.report_id ASC`, append([]interface{}{models.ReportOpen}, args...)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var rep models.Report
+			if err := rows.Scan(&rep.ID, &rep.ReporterID, &rep.ReporterUsername, &rep.TargetType, &rep.TargetID,
+				&rep.Reason, &rep.Status, &rep.CreatedAt); err != nil {
+				return err
+			}
+			if i, ok := index[rep.TargetType+":"+rep.TargetID]; ok {
+				targets[i].Reports = append(targets[i].Reports, rep)
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// Resolve records a moderator's action on a post or comment and closes all of
+// its open reports in one transaction. Hiding also sets the content's
+// hidden_at. Fails with ErrNoOpenReports when there is nothing to resolve.
+func (r *ReportRepository) Resolve(action models.ModerationAction) (*models.ModerationAction, error) {
+	action.ID = utils.GenerateUUID()
+	action.CreatedAt = sortableNow()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO moderation_actions (action_id, moderator_id, action, target_type, target_id, target_user_id, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		action.ID, action.ModeratorID, action.Action, action.TargetType, action.TargetID, action.TargetUserID, action.Note, sortableTime(action.CreatedAt))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Exec(`UPDATE reports SET status = ?, resolved_at = ?, action_id = ?
+		WHERE status = ? AND target_type = ? AND target_id = ?`,
+		models.ReportResolved, action.CreatedAt, action.ID, models.ReportOpen, action.TargetType, action.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNoOpenReports
+	}
+
+	if action.Action == models.ModerationHide {
+		query := `UPDATE posts SET hidden_at = ? WHERE post_id = ? AND hidden_at IS NULL`
+		if action.TargetType == models.TargetComment {
+			query = `UPDATE comments SET hidden_at = ? WHERE comment_id = ? AND hidden_at IS NULL`
+		}
+		if _, err := tx.Exec(query, action.CreatedAt, action.TargetID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// ListActions returns one page of the moderation history matching the filter,
+// newest first. When moderatorID is set only actions on content in the
+// categories that user moderates are included.
+func (r *ReportRepository) ListActions(moderatorID string, filter models.ModerationFilter, page models.Page) ([]models.ModerationAction, *models.Cursor, error) {
+	var conditions []string
+	var args []interface{}
+	for _, f := range []struct{ column, value string }{
+		{"a.target_type", filter.TargetType},
+		{"a.target_id", filter.TargetID},
+		{"a.target_user_id", filter.TargetUserID},
+		{"a.moderator_id", filter.ModeratorID},
+		{"a.action", filter.Action},
+	} {
+		if f.value != "" {
+			conditions = append(conditions, f.column+" = ?")
+			args = append(args, f.value)
+		}
+	}
+	if moderatorID != "" {
+		// Actions on content that has since been purged have no category left
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM post_categories pc
+			  JOIN category_moderators m ON m.category_id = pc.category_id
+			  WHERE m.user_id = ? AND pc.post_id = CASE a.target_type WHEN 'post' THEN a.target_id
+			  ELSE (SELECT c.post_id FROM comments c WHERE c.comment_id = a.target_id) END)`)
+		args = append(args, moderatorID)
+	}
+	if page.After != nil {
+		condition, keysetArgs := keysetCondition("a.created_at", "a.action_id", page.After, true)
+		conditions = append(conditions, condition)
+		args = append(args, keysetArgs...)
+	}
+
+	query := `SELECT a.action_id, a.moderator_id, a.action, a.target_type, a.target_id, a.target_user_id, a.note, a.created_at,
+			  CAST(a.created_at AS TEXT)
+			  FROM moderation_actions a`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY a.created_at DESC, a.action_id DESC LIMIT ?"
+	args = append(args, pageLimit(page))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	actions := []models.ModerationAction{}
+	var next *models.Cursor
+	var lastCreatedAt string
+	for rows.Next() {
+		if len(actions) == page.Limit {
+			// The extra row only proves there is another page
+			next = &models.Cursor{CreatedAt: lastCreatedAt, ID: actions[len(actions)-1].ID}
+			break
+		}
+		var a models.ModerationAction
+		if err := rows.Scan(&a.ID, &a.ModeratorID, &a.Action, &a.TargetType, &a.TargetID, &a.TargetUserID, &a.Note, &a.CreatedAt, &lastCreatedAt); err != nil {
+			return nil, nil, err
+		}
+		actions = append(actions, a)
+	}
+	return actions, next, rows.Err()
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+var ErrSearchUnavailable = errors.New("search is not available")
+
+// Snippet highlight markers; handlers turn them into HTML after escaping
+const (
+	SnippetMatchStart = "\x01"
+	SnippetMatchEnd   = "\x02"
+)
+
+// maxSearchTerms bounds the size of the generated MATCH expression
+const maxSearchTerms = 10
+
+// SearchRepository runs full-text queries against the FTS5 search index
+type SearchRepository struct {
+	db        *sql.DB
+	available bool
+}
+
+// NewSearchRepository creates a new SearchRepository
+func NewSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db, available: models.SearchAvailable(db)}
+}
+
+// Available reports whether full-text search can be used
+func (r *SearchRepository) Available() bool {
+	return r.available
+}
+
+// matchExpression turns free text into an FTS5 query: every word is quoted so
+// user input can never be parsed as FTS5 syntax, and the last word matches as
+// a prefix so results appear while typing
+func matchExpression(terms string) string {
+	words := strings.Fields(terms)
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	if len(words) > 0 {
+		words[len(words)-1] += "*"
+	}
+	return strings.Join(words, " ")
+}
+
+// Search returns matching posts and comments ordered by relevance, and whether
+// more results exist after this page
+func (r *SearchRepository) Search(q models.SearchQuery) ([]models.SearchResult, bool, error) {
+	if !r.available {
+		return nil, false, ErrSearchUnavailable
+	}
+
+	match := matchExpression(q.Terms)
+	if match == "" {
+		return []models.SearchResult{}, false, nil
+	}
+
+	// Titles weigh more than body text
+	query := `SELECT d.kind, d.target_id, d.post_id, p.title, pu.username, cu.username, p.created_at, c.created_at,
+			  snippet(search_index, -1, ?, ?, '…', 16), bm25(search_index, 5.0, 1.0) AS score
+			  FROM search_index
+			  JOIN search_docs d ON d.doc_id = search_index.rowid
+			  JOIN posts p ON p.post_id = d.post_id
+			  JOIN user pu ON pu.user_id = p.user_id
+			  LEFT JOIN comments c ON d.kind = 'comment' AND c.comment_id = d.target_id
+			  LEFT JOIN user cu ON cu.user_id = c.user_id
+			  WHERE search_index MATCH ? AND p.hidden_at IS NULL AND c.hidden_at IS NULL
+			  AND p.deleted_at IS NULL AND c.deleted_at IS NULL`
+	args := []interface{}{SnippetMatchStart, SnippetMatchEnd, match}
+	if q.CategoryID != 0 {
+		query += ` AND EXISTS (SELECT 1 FROM post_categories pc WHERE pc.post_id = d.post_id AND pc.category_id = ?)`
+		args = append(args, q.CategoryID)
+	}
+	query += ` ORDER BY score, d.doc_id LIMIT ? OFFSET ?`
+	args = append(args, q.Limit+1, q.Offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	hasMore := false
+	for rows.Next() {
+		if len(results) == q.Limit {
+			hasMore = true
+			break
+		}
+		var res models.SearchResult
+		var targetID string
+		var commentUsername sql.NullString
+		var commentCreatedAt *time.Time
+		err := rows.Scan(&res.Kind, &targetID, &res.PostID, &res.PostTitle, &res.Username, &commentUsername,
+			&res.CreatedAt, &commentCreatedAt, &res.Snippet, &res.Score)
+		if err != nil {
+			return nil, false, err
+		}
+		if res.Kind == models.TargetComment {
+			res.CommentID = &targetID
+			res.Username = commentUsername.String
+			if commentCreatedAt != nil {
+				res.CreatedAt = *commentCreatedAt
+			}
+		}
+		results = append(results, res)
+	}
+	return results, hasMore, rows.Err()
+}
@@ -0,0 +1,240 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"database/sql"
+	"testing"
+
+	"forum/models"
+)
+
+// newTestSearch creates a database with search enabled and a user to write as
+func newTestSearch(t *testing.T) (*sql.DB, *SearchRepository) {
+	t.Helper()
+	db := newTestDB(t)
+	if _, err := db.Exec(`INSERT INTO user (user_id, username, email) VALUES ('searcher', 'searcher', 'searcher@example.com')`); err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	repo := NewSearchRepository(db)
+	if !repo.Available() {
+		t.Fatal("search is not available although the test was built with sqlite_fts5")
+	}
+	return db, repo
+}
+
+// searchTargets returns the IDs of the posts and comments matching terms
+func searchTargets(t *testing.T, repo *SearchRepository, q models.SearchQuery) []string {
+	t.Helper()
+	if q.Limit == 0 {
+		q.Limit = 50
+	}
+	results, _, err := repo.Search(q)
+	if err != nil {
+		t.Fatalf("search %q: %v", q.Terms, err)
+	}
+	ids := make([]string, 0, len(results))
+	for _, res := range results {
+		if res.CommentID != nil {
+			ids = append(ids, *res.CommentID)
+		} else {
+			ids = append(ids, res.PostID)
+		}
+	}
+	return ids
+}
+
+func expectTargets(t *testing.T, repo *SearchRepository, q models.SearchQuery, want ...string) {
+	t.Helper()
+	got := searchTargets(t, repo, q)
+	if len(got) != len(want) {
+		t.Fatalf("search %q (category %d): got %v, want %v", q.Terms, q.CategoryID, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("search %q (category %d): got %v, want %v", q.Terms, q.CategoryID, got, want)
+		}
+	}
+}
+
+func createSearchPost(t *testing.T, db *sql.DB, title, content string, categoryIDs ...int) *models.Post {
+	t.Helper()
+	post, err := NewPostRepository(db).Create(models.Post{UserID: "searcher", CategoryIDs: categoryIDs, Title: title, Content: content})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	return post
+}
+
+func createSearchComment(t *testing.T, db *sql.DB, postID, content string) *models.Comment {
+	t.Helper()
+	comment, err := NewCommentRepository(db).Create(models.Comment{PostID: postID, UserID: "searcher", Content: content})
+	if err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	return comment
+}
+
+func TestSearchIndexFollowsWrites(t *testing.T) {
+	db, repo := newTestSearch(t)
+	posts := NewPostRepository(db)
+	comments := NewCommentRepository(db)
+
+	// Insert
+	post := createSearchPost(t, db, "Zanzibar spices", "Cloves and nutmeg", 1)
+	comment := createSearchComment(t, db, post.ID, "Platypus sighting")
+	expectTargets(t, repo, models.SearchQuery{Terms: "zanzibar"}, post.ID)
+	expectTargets(t, repo, models.SearchQuery{Terms: "nutmeg"}, post.ID)
+	expectTargets(t, repo, models.SearchQuery{Terms: "platypus"}, comment.ID)
+
+	// Update
+	post.Title = "Quokka photos"
+	if _, err := posts.Update(*post); err != nil {
+		t.Fatalf("update post: %v", err)
+	}
+	comment.Content = "Wombat sighting"
+	if _, err := comments.Update(*comment); err != nil {
+		t.Fatalf("update comment: %v", err)
+	}
+	expectTargets(t, repo, models.SearchQuery{Terms: "zanzibar"})
+	expectTargets(t, repo, models.SearchQuery{Terms: "quokka"}, post.ID)
+	expectTargets(t, repo, models.SearchQuery{Terms: "platypus"})
+	expectTargets(t, repo, models.SearchQuery{Terms: "wombat"}, comment.ID)
+
+	// Delete, as the trash purge does
+	if _, err := db.Exec(`DELETE FROM comments WHERE comment_id = ?`, comment.ID); err != nil {
+		t.Fatalf("delete comment: %v", err)
+	}
+	expectTargets(t, repo, models.SearchQuery{Terms: "wombat"})
+	if _, err := db.Exec(`DELETE FROM posts WHERE post_id = ?`, post.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	expectTargets(t, repo, models.SearchQuery{Terms: "quokka"})
+
+	var docs int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM search_docs WHERE post_id = ?`, post.ID).Scan(&docs); err != nil {
+		t.Fatalf("count search docs: %v", err)
+	}
+	if docs != 0 {
+		t.Errorf("%d search docs left for the deleted post", docs)
+	}
+}
+
+func TestSearchSkipsHiddenAndDeletedContent(t *testing.T) {
+	db, repo := newTestSearch(t)
+	posts := NewPostRepository(db)
+	comments := NewCommentRepository(db)
+
+	hiddenPost := createSearchPost(t, db, "Marmalade hidden", "Content", 1)
+	underHidden := createSearchComment(t, db, hiddenPost.ID, "Marmalade under a hidden post")
+	deletedPost := createSearchPost(t, db, "Marmalade deleted", "Content", 1)
+	visiblePost := createSearchPost(t, db, "Marmalade visible", "Content", 1)
+	hiddenComment := createSearchComment(t, db, visiblePost.ID, "Marmalade hidden comment")
+	deletedComment := createSearchComment(t, db, visiblePost.ID, "Marmalade deleted comment")
+
+	for _, stmt := range []struct {
+		query string
+		id    string
+	}{
+		{`UPDATE posts SET hidden_at = CURRENT_TIMESTAMP WHERE post_id = ?`, hiddenPost.ID},
+		{`UPDATE comments SET hidden_at = CURRENT_TIMESTAMP WHERE comment_id = ?`, hiddenComment.ID},
+	} {
+		if _, err := db.Exec(stmt.query, stmt.id); err != nil {
+			t.Fatalf("hide: %v", err)
+		}
+	}
+	if err := posts.Delete(deletedPost.ID, "searcher"); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	if err := comments.Delete(deletedComment.ID, "searcher"); err != nil {
+		t.Fatalf("delete comment: %v", err)
+	}
+
+	got := searchTargets(t, repo, models.SearchQuery{Terms: "marmalade"})
+	if len(got) != 1 || got[0] != visiblePost.ID {
+		t.Errorf("search found %v, want only the visible post %s", got, visiblePost.ID)
+	}
+	for _, id := range []string{hiddenPost.ID, underHidden.ID, deletedPost.ID, hiddenComment.ID, deletedComment.ID} {
+		for _, found := range got {
+			if found == id {
+				t.Errorf("search found %s, which is hidden or deleted", id)
+			}
+		}
+	}
+}
+
+func TestSearchFiltersByCategory(t *testing.T) {
+	db, repo := newTestSearch(t)
+
+	first := createSearchPost(t, db, "Gazpacho recipe", "Content", 1)
+	both := createSearchPost(t, db, "Gazpacho again", "Content", 2, 1)
+	comment := createSearchComment(t, db, both.ID, "Gazpacho needs bread")
+
+	got := searchTargets(t, repo, models.SearchQuery{Terms: "gazpacho", CategoryID: 2})
+	if len(got) != 2 || !contains(got, both.ID) || !contains(got, comment.ID) {
+		t.Errorf("category 2: got %v, want %s and its comment %s", got, both.ID, comment.ID)
+	}
+	got = searchTargets(t, repo, models.SearchQuery{Terms: "gazpacho", CategoryID: 1})
+	if len(got) != 3 || !contains(got, first.ID) {
+		t.Errorf("category 1: got %v, want both posts and the comment", got)
+	}
+	expectTargets(t, repo, models.SearchQuery{Terms: "gazpacho", CategoryID: 3})
+}
+
+func TestSearchPages(t *testing.T) {
+	db, repo := newTestSearch(t)
+	for i := 0; i < 5; i++ {
+		createSearchPost(t, db, "Origami", "Paper folding", 1)
+	}
+
+	seen := make(map[string]bool)
+	q := models.SearchQuery{Terms: "origami", Limit: 2}
+	for _, wantMore := range []bool{true, true, false} {
+		results, hasMore, err := repo.Search(q)
+		if err != nil {
+			t.Fatalf("search at offset %d: %v", q.Offset, err)
+		}
+		if hasMore != wantMore {
+			t.Errorf("offset %d: hasMore = %v, want %v", q.Offset, hasMore, wantMore)
+		}
+		for _, res := range results {
+			if seen[res.PostID] {
+				t.Errorf("offset %d: %s returned twice", q.Offset, res.PostID)
+			}
+			seen[res.PostID] = true
+		}
+		q.Offset += q.Limit
+	}
+	if len(seen) != 5 {
+		t.Errorf("paged through %d posts, want 5", len(seen))
+	}
+}
+
+// -rebuild-search runs models.RebuildSearchIndex
+func TestRebuildSearchIndex(t *testing.T) {
+	db, repo := newTestSearch(t)
+	post := createSearchPost(t, db, "Kumquat harvest", "Content", 1)
+	comment := createSearchComment(t, db, post.ID, "Kumquat jam")
+
+	if _, err := db.Exec(`DELETE FROM search_index; DELETE FROM search_docs;`); err != nil {
+		t.Fatalf("clear index: %v", err)
+	}
+	expectTargets(t, repo, models.SearchQuery{Terms: "kumquat"})
+
+	if err := models.RebuildSearchIndex(db); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	got := searchTargets(t, repo, models.SearchQuery{Terms: "kumquat"})
+	if len(got) != 2 || !contains(got, post.ID) || !contains(got, comment.ID) {
+		t.Errorf("after rebuild: got %v, want %s and %s", got, post.ID, comment.ID)
+	}
+}
+
+func contains(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
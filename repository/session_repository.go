@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"forum/config"
 	"forum/models"
 	"forum/utils"
 )
@@ -24,79 +25,198 @@ func NewSessionRepository(db *sql.DB) *SessionRepository {
 	return &SessionRepository{DB: db}
 }
 
-// Create creates a new session for a user
-func (r *SessionRepository) Create(userID, ipAddress string) (*models.Session, error) {
-	// First, delete any existing sessions for this user
-	_, err := r.DB.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+// lastSeenInterval limits how often a session is renewed, so that busy
+// sessions do not cost an UPDATE on every request
+const lastSeenInterval = time.Minute
+
+// maxUserAgentLength caps the user agent stored with a session
+const maxUserAgentLength = 255
+
+const sessionColumns = "id, user_id, token_hash, ip_address, user_agent, remember_me, created_at, last_seen_at, expires_at, absolute_expires_at"
+
+// sessionTimeouts returns the idle and absolute timeouts for a session
+func sessionTimeouts(rememberMe bool) (idle, absolute time.Duration) {
+	if rememberMe {
+		return config.RememberMeIdleTimeout, config.RememberMeAbsoluteTimeout
+	}
+	return config.SessionIdleTimeout, config.SessionAbsoluteTimeout
+}
+
+// Create creates a new session for a user. Other sessions of the user stay
+// valid; only expired ones are cleaned up.
+func (r *SessionRepository) Create(userID, ipAddress, userAgent string, rememberMe bool) (*models.Session, error) {
+	_, err := r.DB.Exec("DELETE FROM sessions WHERE user_id = ? AND julianday(expires_at) <= julianday('now')", userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate a new session ID
-	sessionID := utils.GenerateSessionToken()
-	expiresAt := utils.CalculateSessionExpiry()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := time.Now()
+	idle, absolute := sessionTimeouts(rememberMe)
+	token := utils.GenerateToken()
+	session := &models.Session{
+		ID:                utils.GenerateUUID(),
+		UserID:            userID,
+		SessionID:         token,
+		TokenHash:         utils.HashToken(token),
+		IPAddress:         ipAddress,
+		UserAgent:         userAgent,
+		RememberMe:        rememberMe,
+		CreatedAt:         now,
+		LastSeenAt:        now,
+		AbsoluteExpiresAt: now.Add(absolute),
+	}
+	session.ExpiresAt = utils.CalculateSessionExpiry(now, session.AbsoluteExpiresAt, idle)
 
-	// Insert the new session
 	_, err = r.DB.Exec(
-		"INSERT INTO sessions (user_id, session_id, ip_address, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
-		userID, sessionID, ipAddress, time.Now(), expiresAt,
+		"INSERT INTO sessions ("+sessionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		session.ID, session.UserID, session.TokenHash, session.IPAddress, session.UserAgent, session.RememberMe,
+		session.CreatedAt, session.LastSeenAt, session.ExpiresAt, session.AbsoluteExpiresAt,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// Return the session
-	session := &models.Session{
-		UserID:    userID,
-		SessionID: sessionID,
-		IPAddress: ipAddress,
-		CreatedAt: time.Now(),
-		ExpiresAt: expiresAt,
-	}
-
 	return session, nil
 }
 
-// GetBySessionID retrieves a session by its ID
-func (r *SessionRepository) GetBySessionID(sessionID string) (*models.Session, error) {
+// scanSession reads a row selected with sessionColumns
+func scanSession(row interface{ Scan(...any) error }) (*models.Session, error) {
 	var session models.Session
-	var createdStr, expiresStr string
-
-	err := r.DB.QueryRow(
-		"SELECT user_id, session_id, ip_address, created_at, expires_at FROM sessions WHERE session_id = ?",
-		sessionID,
-	).Scan(&session.UserID, &session.SessionID, &session.IPAddress, &createdStr, &expiresStr)
+	var ipAddress, userAgent sql.NullString
+	var createdStr, lastSeenStr, expiresStr, absoluteStr string
 
+	err := row.Scan(&session.ID, &session.UserID, &session.TokenHash, &ipAddress, &userAgent, &session.RememberMe,
+		&createdStr, &lastSeenStr, &expiresStr, &absoluteStr)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrSessionNotFound
-		}
 		return nil, err
 	}
+	session.IPAddress = ipAddress.String
+	session.UserAgent = userAgent.String
 
 	// Parse timestamps
-	session.CreatedAt, err = time.Parse(time.RFC3339, createdStr)
-	if err != nil {
+	if session.CreatedAt, err = time.Parse(time.RFC3339, createdStr); err != nil {
+		return nil, err
+	}
+	if session.LastSeenAt, err = time.Parse(time.RFC3339, lastSeenStr); err != nil {
+		return nil, err
+	}
+	if session.ExpiresAt, err = time.Parse(time.RFC3339, expiresStr); err != nil {
+		return nil, err
+	}
+	if session.AbsoluteExpiresAt, err = time.Parse(time.RFC3339, absoluteStr); err != nil {
 		return nil, err
 	}
 
-	session.ExpiresAt, err = time.Parse(time.RFC3339, expiresStr)
+	return &session, nil
+}
+
+// GetBySessionID retrieves a session by its cookie token
+func (r *SessionRepository) GetBySessionID(sessionID string) (*models.Session, error) {
+	tokenHash := utils.HashToken(sessionID)
+	session, err := scanSession(r.DB.QueryRow(
+		"SELECT "+sessionColumns+" FROM sessions WHERE token_hash = ?",
+		tokenHash,
+	))
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
 		return nil, err
 	}
+	session.SessionID = sessionID
 
 	// Check if session is expired
 	if time.Now().After(session.ExpiresAt) {
 		// Delete the expired session
-		_, _ = r.DB.Exec("DELETE FROM sessions WHERE session_id = ?", sessionID)
+		_, _ = r.DB.Exec("DELETE FROM sessions WHERE token_hash = ?", tokenHash)
 		return nil, ErrSessionExpired
 	}
 
-	return &session, nil
+	return session, nil
+}
+
+// ListByUser returns the user's active sessions, most recently used first
+func (r *SessionRepository) ListByUser(userID string) ([]models.Session, error) {
+	rows, err := r.DB.Query(
+		"SELECT "+sessionColumns+" FROM sessions WHERE user_id = ? AND julianday(expires_at) > julianday('now') ORDER BY julianday(last_seen_at) DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+// Renew records that the session was just used and pushes its idle expiry
+// back, never past the absolute expiry. Writes are throttled to one per
+// lastSeenInterval; the result reports whether the session was renewed.
+func (r *SessionRepository) Renew(session *models.Session) (bool, error) {
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < lastSeenInterval {
+		return false, nil
+	}
+
+	idle, _ := sessionTimeouts(session.RememberMe)
+	expiresAt := utils.CalculateSessionExpiry(now, session.AbsoluteExpiresAt, idle)
+	_, err := r.DB.Exec("UPDATE sessions SET last_seen_at = ?, expires_at = ? WHERE id = ?", now, expiresAt, session.ID)
+	if err != nil {
+		return false, err
+	}
+	session.LastSeenAt = now
+	session.ExpiresAt = expiresAt
+	return true, nil
 }
 
-// Delete removes a session
+// Delete removes a session by its cookie token
 func (r *SessionRepository) Delete(sessionID string) error {
-	_, err := r.DB.Exec("DELETE FROM sessions WHERE session_id = ?", sessionID)
+	_, err := r.DB.Exec("DELETE FROM sessions WHERE token_hash = ?", utils.HashToken(sessionID))
 	return err
 }
+
+// DeleteForUser removes one of the user's sessions by its public ID
+func (r *SessionRepository) DeleteForUser(userID, id string) error {
+	result, err := r.DB.Exec("DELETE FROM sessions WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteOthers removes every session of the user except keepID and returns how many were removed
+func (r *SessionRepository) DeleteOthers(userID, keepID string) (int64, error) {
+	result, err := r.DB.Exec("DELETE FROM sessions WHERE user_id = ? AND id != ?", userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteAllForUser removes every session of the user and returns how many were removed
+func (r *SessionRepository) DeleteAllForUser(userID string) (int64, error) {
+	result, err := r.DB.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
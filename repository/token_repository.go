@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"forum/utils"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Token purposes
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
+// TokenRepository handles the single-use tokens sent by mail
+type TokenRepository struct {
+	DB *sql.DB
+}
+
+// NewTokenRepository creates a new TokenRepository
+func NewTokenRepository(db *sql.DB) *TokenRepository {
+	return &TokenRepository{DB: db}
+}
+
+// Create issues a token for the user and returns it. Earlier tokens of the
+// user for the same purpose stop working.
+func (r *TokenRepository) Create(userID, purpose string, ttl time.Duration) (string, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_tokens WHERE user_id = ? AND purpose = ?", userID, purpose); err != nil {
+		return "", err
+	}
+
+	token := utils.GenerateToken()
+	now := time.Now()
+	_, err = tx.Exec(
+		"INSERT INTO user_tokens (token_hash, user_id, purpose, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		utils.HashToken(token), userID, purpose, now, now.Add(ttl),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Consume checks a token and deletes it, returning the user it was issued to.
+// Unknown, expired and wrong-purpose tokens all give ErrInvalidToken.
+func (r *TokenRepository) Consume(token, purpose string) (string, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	tokenHash := utils.HashToken(token)
+	var userID string
+	var expiresAt time.Time
+	err = tx.QueryRow(
+		"SELECT user_id, expires_at FROM user_tokens WHERE token_hash = ? AND purpose = ?",
+		tokenHash, purpose,
+	).Scan(&userID, &expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrInvalidToken
+		}
+		return "", err
+	}
+
+	// Of two requests racing for the same token, only the one that deletes it wins
+	res, err := tx.Exec("DELETE FROM user_tokens WHERE token_hash = ?", tokenHash)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", ErrInvalidToken
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	if time.Now().After(expiresAt) {
+		return "", ErrInvalidToken
+	}
+	return userID, nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+var ErrNotInTrash = errors.New("content is not in the trash")
+
+// trashDeletedAt is deleted_at in sortableTimeLayout. Timestamps lose their type
+// in the UNION below, and the normalized text also orders the trash and its cursors.
+const trashDeletedAt = `strftime('%Y-%m-%dT%H:%M:%fZ', t.deleted_at)`
+
+// trashQuery selects deleted posts and comments together
+const trashQuery = `SELECT t.target_type, t.target_id, t.post_id, t.user_id, u.username, t.title, t.content,
+		` + trashDeletedAt + `, COALESCE(t.deleted_by, '')
+		FROM (
+			SELECT 'post' AS target_type, post_id AS target_id, post_id, user_id, title, content, deleted_at, deleted_by
+			FROM posts WHERE deleted_at IS NOT NULL
+			UNION ALL
+			SELECT 'comment', comment_id, post_id, user_id, '', content, deleted_at, deleted_by
+			FROM comments WHERE deleted_at IS NOT NULL
+		) t
+		JOIN user u ON u.user_id = t.user_id`
+
+// TrashRepository lists, restores and purges soft-deleted posts and comments
+type TrashRepository struct {
+	db *sql.DB
+}
+
+// NewTrashRepository creates a new TrashRepository
+func NewTrashRepository(db *sql.DB) *TrashRepository {
+	return &TrashRepository{db: db}
+}
+
+// List returns one page of the trash, most recently deleted first. When
+// moderatorID is set only content in the categories that user moderates is included.
+func (r *TrashRepository) List(moderatorID string, page models.Page) ([]models.TrashedItem, *models.Cursor, error) {
+	const itemKey = `t.target_type || ':' || t.target_id`
+
+	var conditions []string
+	var args []interface{}
+	if moderatorID != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM post_categories pc
+			  JOIN category_moderators m ON m.category_id = pc.category_id
+			  WHERE pc.post_id = t.post_id AND m.user_id = ?)`)
+		args = append(args, moderatorID)
+	}
+	if page.After != nil {
+		condition, keysetArgs := keysetCondition(trashDeletedAt, itemKey, page.After, true)
+		conditions = append(conditions, condition)
+		args = append(args, keysetArgs...)
+	}
+
+	query := trashQuery
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY ` + trashDeletedAt + ` DESC, ` + itemKey + ` DESC LIMIT ?`
+	args = append(args, pageLimit(page))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	items := []models.TrashedItem{}
+	var next *models.Cursor
+	for rows.Next() {
+		if len(items) == page.Limit {
+			// The extra row only proves there is another page
+			last := items[len(items)-1]
+			next = &models.Cursor{CreatedAt: sortableTime(last.DeletedAt), ID: last.TargetType + ":" + last.TargetID}
+			break
+		}
+		item, err := scanTrashedItem(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, *item)
+	}
+	return items, next, rows.Err()
+}
+
+// Get returns a post or comment from the trash, or ErrNotInTrash
+func (r *TrashRepository) Get(targetType, targetID string) (*models.TrashedItem, error) {
+	item, err := scanTrashedItem(r.db.QueryRow(trashQuery+` WHERE t.target_type = ? AND t.target_id = ?`, targetType, targetID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotInTrash
+	}
+	return item, err
+}
+
+func scanTrashedItem(row interface{ Scan(...interface{}) error }) (*models.TrashedItem, error) {
+	var item models.TrashedItem
+	var deletedAt string
+	err := row.Scan(&item.TargetType, &item.TargetID, &item.PostID, &item.AuthorID, &item.AuthorUsername,
+		&item.Title, &item.Content, &deletedAt, &item.DeletedBy)
+	if err != nil {
+		return nil, err
+	}
+	if item.DeletedAt, err = time.Parse(time.RFC3339, deletedAt); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Restore takes a post or comment out of the trash
+func (r *TrashRepository) Restore(targetType, targetID string) error {
+	query := `UPDATE posts SET deleted_at = NULL, deleted_by = NULL WHERE post_id = ? AND deleted_at IS NOT NULL`
+	if targetType == models.TargetComment {
+		query = `UPDATE comments SET deleted_at = NULL, deleted_by = NULL WHERE comment_id = ? AND deleted_at IS NOT NULL`
+	}
+	res, err := r.db.Exec(query, targetID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotInTrash
+	}
+	return nil
+}
+
+// Purge permanently deletes posts and comments deleted before the given time
+// and returns how many were removed. Purged posts take their comments and
+// reactions with them. Deleted comments that still have replies are kept as
+// placeholders until their replies are gone.
+func (r *TrashRepository) Purge(before time.Time) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM posts WHERE deleted_at IS NOT NULL AND julianday(deleted_at) <= julianday(?)`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	purged, _ := res.RowsAffected()
+
+	// Removing a reply can free its deleted parent, so repeat until nothing changes
+	for {
+		res, err := tx.Exec(`DELETE FROM comments
+			WHERE deleted_at IS NOT NULL AND julianday(deleted_at) <= julianday(?)
+			AND NOT EXISTS (SELECT 1 FROM comments reply WHERE reply.parent_comment_id = comments.comment_id)`, before.UTC())
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		if n == 0 {
+			break
+		}
+		purged += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
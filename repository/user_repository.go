@@ -3,7 +3,7 @@ package repository
 import (
 	"database/sql"
 	"errors"
-	"fmt"
+	"log/slog"
 	"time"
 
 	"forum/models"
@@ -92,6 +92,7 @@ func (r *UserRepository) Create(reg models.UserRegistration) (*models.User, erro
 		ID:        userID,
 		Username:  reg.Username,
 		Email:     reg.Email,
+		Role:      models.RoleUser,
 		CreatedAt: createdAt,
 	}
 
@@ -105,9 +106,9 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	var createdAt time.Time
 
 	err := r.DB.QueryRow(
-		"SELECT user_id, username, email, created_at FROM user WHERE email = ?",
+		"SELECT user_id, username, email, email_verified_at IS NOT NULL, role, created_at FROM user WHERE email = ?",
 		email,
-	).Scan(&user.ID, &user.Username, &user.Email, &createdAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.Role, &createdAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -132,9 +133,9 @@ func (r *UserRepository) GetByID(id string) (*models.User, error) {
 	//var timestamp string
 	var createdAt time.Time
 	err := r.DB.QueryRow(
-		"SELECT user_id, username, email, created_at FROM user WHERE user_id = ?",
+		"SELECT user_id, username, email, email_verified_at IS NOT NULL, role, created_at FROM user WHERE user_id = ?",
 		id,
-	).Scan(&user.ID, &user.Username, &user.Email, &createdAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.Role, &createdAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -174,26 +175,97 @@ func (r *UserRepository) GetAuthByUserID(userID string) (*models.UserAuth, error
 // Authenticate validates a user's login credentials
 func (r *UserRepository) Authenticate(login models.UserLogin) (*models.User, error) {
 	// Get the user by email
-	fmt.Println(login.Email)
 	user, err := r.GetByEmail(login.Email)
 	if err != nil {
-		fmt.Println("User email not found")
 		return nil, ErrInvalidCredentials
 	}
 
 	// Get the user's authentication data
 	auth, err := r.GetAuthByUserID(user.ID)
 	if err != nil {
-		fmt.Println("Auth record not found")
+		slog.Error("Auth record not found", "user_id", user.ID, "error", err)
 		return nil, err
 	}
-	fmt.Println("Stored hash:", auth.PasswordHash)
-	fmt.Println("Password match?", utils.CheckPasswordHash(login.Password, auth.PasswordHash))
 
 	// Check the password
 	if !utils.CheckPasswordHash(login.Password, auth.PasswordHash) {
 		return nil, ErrInvalidCredentials
 	}
 
+	// Upgrade hashes made with an older algorithm or cost while the plain
+	// password is at hand. A failure here must not fail the login.
+	if utils.NeedsRehash(auth.PasswordHash) {
+		if err := r.UpdatePassword(user.ID, login.Password); err != nil {
+			slog.Error("Failed to rehash password", "user_id", user.ID, "error", err)
+		}
+	}
+
 	return user, nil
 }
+
+// CheckPassword compares password with the user's stored hash
+func (r *UserRepository) CheckPassword(userID, password string) error {
+	auth, err := r.GetAuthByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(password, auth.PasswordHash) {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// UpdatePassword hashes and stores a new password for the user
+func (r *UserRepository) UpdatePassword(userID, password string) error {
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.DB.Exec("UPDATE user_auth SET password_hash = ? WHERE user_id = ?", passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// MarkEmailVerified records that the user confirmed their email address
+func (r *UserRepository) MarkEmailVerified(userID string) error {
+	_, err := r.DB.Exec(
+		"UPDATE user SET email_verified_at = ? WHERE user_id = ? AND email_verified_at IS NULL",
+		time.Now(), userID,
+	)
+	return err
+}
+
+// SetRole changes the user's role. Demoting a user to a plain user also ends
+// their category moderator assignments.
+func (r *UserRepository) SetRole(userID, role string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE user SET role = ? WHERE user_id = ?", role, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+
+	if role == models.RoleUser {
+		if _, err := tx.Exec("DELETE FROM category_moderators WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -4,8 +4,11 @@ import (
 	"database/sql"
 	"net/http"
 
+	"forum/config"
 	"forum/handlers"
+	"forum/mailer"
 	"forum/middleware"
+	"forum/models"
 	"forum/repository"
 )
 
@@ -19,18 +22,51 @@ func SetupRoutes(db *sql.DB) http.Handler {
 	postRepo := repository.NewPostRepository(db)
 	commentRepo := repository.NewCommentRepository(db)
 	reactionRepo := repository.NewReactionRepository(db)
+	searchRepo := repository.NewSearchRepository(db)
+	lockoutRepo := repository.NewLockoutRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	reportRepo := repository.NewReportRepository(db)
+	banRepo := repository.NewBanRepository(db)
+	trashRepo := repository.NewTrashRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+
+	// Login throttling is shared by the auth handler
+	loginThrottle := middleware.NewLoginThrottle(lockoutRepo)
+	mail := mailer.New()
+
+	// Every audited handler writes through the same logger
+	audit := handlers.NewAuditLogger(auditRepo)
 
 	// Create handlers
-	authHandler := handlers.NewAuthHandler(userRepo, sessionRepo)
+	accountHandler := handlers.NewAccountHandler(userRepo, sessionRepo, tokenRepo, mail, audit)
+	authHandler := handlers.NewAuthHandler(userRepo, sessionRepo, categoryRepo, banRepo, accountHandler, loginThrottle, audit)
 	categoryHandler := handlers.NewCategoryHandler(categoryRepo)
-	postHandler := handlers.NewPostHandler(postRepo)
-	commentHandler := handlers.NewCommentHandler(commentRepo)
+	postHandler := handlers.NewPostHandler(postRepo, categoryRepo, commentRepo, reactionRepo, audit)
+	commentHandler := handlers.NewCommentHandler(commentRepo, reactionRepo, postRepo, categoryRepo, audit)
+	reactionHandler := handlers.NewReactionHandler(reactionRepo, postRepo, commentRepo)
+	searchHandler := handlers.NewSearchHandler(searchRepo)
+	sessionHandler := handlers.NewSessionHandler(sessionRepo)
+	roleHandler := handlers.NewRoleHandler(userRepo, categoryRepo, audit)
+	reportHandler := handlers.NewReportHandler(reportRepo, postRepo, commentRepo, categoryRepo, userRepo, mail, audit)
+	banHandler := handlers.NewBanHandler(banRepo, userRepo, sessionRepo, audit)
+	trashHandler := handlers.NewTrashHandler(trashRepo, postRepo, categoryRepo, audit)
+	auditHandler := handlers.NewAuditHandler(auditRepo)
+	lockoutHandler := handlers.NewLockoutHandler(lockoutRepo)
 
 	// Create middleware
-	registerLimiter := middleware.NewRateLimiter()
-	authMiddleware := middleware.NewAuthMiddleware(sessionRepo, userRepo)
+	registerLimiter := middleware.NewRateLimiter("registration", config.RegisterRateLimit)
+	postLimiter := middleware.NewRateLimiter("posting", config.PostRateLimit, http.MethodPost)
+	commentLimiter := middleware.NewRateLimiter("comment", config.CommentRateLimit, http.MethodPost)
+	reactionLimiter := middleware.NewRateLimiter("reaction", config.ReactionRateLimit, http.MethodPost)
+	searchLimiter := middleware.NewRateLimiter("search", config.SearchRateLimit, http.MethodGet)
+	mailLimiter := middleware.NewRateLimiter("mail", config.MailRateLimit, http.MethodPost)
+	reportLimiter := middleware.NewRateLimiter("report", config.ReportRateLimit, http.MethodPost)
+	authMiddleware := middleware.NewAuthMiddleware(sessionRepo, userRepo, banRepo)
+	requireModerator := authMiddleware.RequireRole(models.RoleModerator)
+	requireAdmin := authMiddleware.RequireRole(models.RoleAdmin)
 	guestHandler := handlers.NewGuestHandler(categoryRepo, postRepo, commentRepo, reactionRepo)
-	corsMiddleware := middleware.NewCORSMiddleware("http://localhost:8081")
+	corsMiddleware := middleware.NewCORSMiddleware(config.PublicOrigin)
+	csrfMiddleware := middleware.NewCSRFMiddleware(config.PublicOrigin)
 
 	// Create router (using standard net/http for simplicity)
 	mux := http.NewServeMux()
@@ -38,12 +74,41 @@ func SetupRoutes(db *sql.DB) http.Handler {
 	// Define auth routes
 	mux.Handle("/forum/api/guest", corsMiddleware.Handler(http.HandlerFunc(guestHandler.GetGuestData)))
 	mux.Handle("/forum/api/categories", corsMiddleware.Handler(http.HandlerFunc(categoryHandler.GetCategories)))
-	mux.Handle("/forum/api/register", corsMiddleware.Handler(http.HandlerFunc(registerLimiter.Limit(authHandler.Register))))
-	mux.Handle("/forum/api/session/login", corsMiddleware.Handler(http.HandlerFunc(authHandler.Login)))
-	mux.HandleFunc("/forum/api/session/logout", authHandler.Logout)
+	mux.Handle("/forum/api/register", corsMiddleware.Handler(csrfMiddleware.CheckOrigin(registerLimiter.Limit(http.HandlerFunc(authHandler.Register)))))
+	mux.Handle("/forum/api/session/login", corsMiddleware.Handler(csrfMiddleware.CheckOrigin(http.HandlerFunc(authHandler.Login))))
+	mux.Handle("/forum/api/session/logout", corsMiddleware.Handler(csrfMiddleware.Protect(http.HandlerFunc(authHandler.Logout))))
 	mux.Handle("/forum/api/session/verify", corsMiddleware.Handler(http.HandlerFunc(authHandler.VerifySession)))
-	mux.Handle("/forum/api/posts", corsMiddleware.Handler(authMiddleware.RequireAuth(http.HandlerFunc(postHandler.CreatePost))))
-	mux.Handle("/forum/api/comments", corsMiddleware.Handler(authMiddleware.RequireAuth(http.HandlerFunc(commentHandler.CreateComment))))
+	mux.Handle("/forum/api/account/password", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.RequireSignedIn(http.HandlerFunc(accountHandler.ChangePassword)))))
+	mux.Handle("/forum/api/account/password/forgot", corsMiddleware.Handler(csrfMiddleware.CheckOrigin(mailLimiter.Limit(http.HandlerFunc(accountHandler.ForgotPassword)))))
+	mux.Handle("/forum/api/account/password/reset", corsMiddleware.Handler(csrfMiddleware.CheckOrigin(http.HandlerFunc(accountHandler.ResetPassword))))
+	mux.Handle("/forum/api/account/email/verify", corsMiddleware.Handler(csrfMiddleware.CheckOrigin(http.HandlerFunc(accountHandler.VerifyEmail))))
+	mux.Handle("/forum/api/account/email/resend", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.RequireSignedIn(mailLimiter.Limit(http.HandlerFunc(accountHandler.ResendVerification))))))
+	mux.Handle("/forum/api/sessions", corsMiddleware.Handler(authMiddleware.RequireSignedIn(http.HandlerFunc(sessionHandler.ListSessions))))
+	mux.Handle("/forum/api/sessions/{id}", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.RequireSignedIn(http.HandlerFunc(sessionHandler.RevokeSession)))))
+	mux.Handle("/forum/api/sessions/revoke-others", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.RequireSignedIn(http.HandlerFunc(sessionHandler.RevokeOtherSessions)))))
+	mux.Handle("/forum/api/posts", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.BlockSuspended(postLimiter.Limit(http.HandlerFunc(postHandler.Posts))))))
+	mux.Handle("/forum/api/posts/{id}", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.BlockSuspended(http.HandlerFunc(postHandler.PostByID)))))
+	mux.Handle("/forum/api/comments", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.BlockSuspended(commentLimiter.Limit(http.HandlerFunc(commentHandler.Comments))))))
+	mux.Handle("/forum/api/comments/{id}", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.BlockSuspended(http.HandlerFunc(commentHandler.CommentByID)))))
+	mux.Handle("/forum/api/search", corsMiddleware.Handler(searchLimiter.Limit(http.HandlerFunc(searchHandler.Search))))
+	mux.Handle("/forum/api/reactions", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.RequireAuth(reactionLimiter.Limit(http.HandlerFunc(reactionHandler.React))))))
+
+	// Reports and moderation
+	mux.Handle("/forum/api/reports", corsMiddleware.Handler(csrfMiddleware.Protect(authMiddleware.RequireAuth(reportLimiter.Limit(http.HandlerFunc(reportHandler.CreateReport))))))
+	mux.Handle("/forum/api/moderation/reports", corsMiddleware.Handler(requireModerator(http.HandlerFunc(reportHandler.Queue))))
+	mux.Handle("/forum/api/moderation/reports/resolve", corsMiddleware.Handler(csrfMiddleware.Protect(requireModerator(http.HandlerFunc(reportHandler.Resolve)))))
+	mux.Handle("/forum/api/moderation/actions", corsMiddleware.Handler(requireModerator(http.HandlerFunc(reportHandler.History))))
+	mux.Handle("/forum/api/moderation/trash", corsMiddleware.Handler(requireModerator(http.HandlerFunc(trashHandler.ListTrash))))
+	mux.Handle("/forum/api/moderation/trash/restore", corsMiddleware.Handler(csrfMiddleware.Protect(requireModerator(http.HandlerFunc(trashHandler.Restore)))))
+
+	// Administration
+	mux.Handle("/forum/api/admin/users/{id}/role", corsMiddleware.Handler(csrfMiddleware.Protect(requireAdmin(http.HandlerFunc(roleHandler.SetUserRole)))))
+	mux.Handle("/forum/api/admin/categories/{id}/moderators", corsMiddleware.Handler(csrfMiddleware.Protect(requireAdmin(http.HandlerFunc(roleHandler.CategoryModerators)))))
+	mux.Handle("/forum/api/admin/categories/{id}/moderators/{user_id}", corsMiddleware.Handler(csrfMiddleware.Protect(requireAdmin(http.HandlerFunc(roleHandler.RemoveCategoryModerator)))))
+	mux.Handle("/forum/api/admin/users/{id}/bans", corsMiddleware.Handler(csrfMiddleware.Protect(requireAdmin(http.HandlerFunc(banHandler.UserBans)))))
+	mux.Handle("/forum/api/admin/bans/{id}", corsMiddleware.Handler(csrfMiddleware.Protect(requireAdmin(http.HandlerFunc(banHandler.LiftBan)))))
+	mux.Handle("/forum/api/admin/lockouts", corsMiddleware.Handler(requireAdmin(http.HandlerFunc(lockoutHandler.ListLockouts))))
+	mux.Handle("/forum/api/admin/audit", corsMiddleware.Handler(requireAdmin(http.HandlerFunc(auditHandler.ListEvents))))
 
 	// Apply middleware to all routes
 	return authMiddleware.Authenticate(mux)
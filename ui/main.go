@@ -1,15 +1,35 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 )
 
+// apiBase is where the pages send their API requests, taken from FORUM_API_URL
+func apiBase() string {
+	if base := strings.TrimRight(os.Getenv("FORUM_API_URL"), "/"); base != "" {
+		return base
+	}
+	return "http://localhost:8080"
+}
+
 func main() {
 	// Serve static assets (css, js, images)
 	fs := http.FileServer(http.Dir("./static"))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
 
+	// The scripts read the API base from config.js, so the same files work
+	// wherever the API is deployed
+	base, _ := json.Marshal(apiBase())
+	http.HandleFunc("/static/js/config.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		fmt.Fprintf(w, "const API_BASE = %s;\n", base)
+	})
+
 	// Serve individual HTML pages
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "./static/templates/index.html")
@@ -27,6 +47,14 @@ func main() {
 		http.ServeFile(w, r, "./static/templates/user.html")
 	})
 
+	// Pages opened from the links in account mails
+	http.HandleFunc("/verify-email", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./static/templates/verify-email.html")
+	})
+	http.HandleFunc("/reset-password", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./static/templates/reset-password.html")
+	})
+
 	// Start the server
 	log.Println("Serving on http://localhost:8081/")
 	if err := http.ListenAndServe(":8081", nil); err != nil {
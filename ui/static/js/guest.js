@@ -13,7 +13,7 @@ async function handleReaction(
   dislikeBtn
 ) {
   try {
-    const res = await fetch("/api/react", {
+    const res = await fetch(`${API_BASE}/forum/api/reactions`, {
       method: "POST",
       credentials: "include",
       headers: {
@@ -28,10 +28,9 @@ async function handleReaction(
 
     if (!res.ok) throw new Error("Failed to react");
 
-    const updatedReactions = await res.json();
-    const { likes, dislikes } = countReactions(updatedReactions);
-    likeBtn.querySelector(".like-count").textContent = likes;
-    dislikeBtn.querySelector(".dislike-count").textContent = dislikes;
+    const { counts } = await res.json();
+    likeBtn.querySelector(".like-count").textContent = counts.like;
+    dislikeBtn.querySelector(".dislike-count").textContent = counts.dislike;
   } catch (err) {
     console.error("Reaction failed:", err);
   }
@@ -49,7 +48,7 @@ document.addEventListener("DOMContentLoaded", async () => {
   const categoryTabs = document.getElementById("category-tabs");
 
   if (!isGuest) {
-    const authResponse = await fetch("/api/verify-session", {
+    const authResponse = await fetch(`${API_BASE}/forum/api/session/verify`, {
       method: "GET",
       credentials: "include",
     });
@@ -286,7 +285,7 @@ document.addEventListener("DOMContentLoaded", async () => {
   }
 
   try {
-    const response = await fetch("http://localhost:8080/forum/api/guest");
+    const response = await fetch(`${API_BASE}/forum/api/guest`);
     if (!response.ok) throw new Error("Network response was not ok");
 
     data = await response.json(); // Assign to outer 'data'
@@ -12,7 +12,7 @@
 
           try {
             const res = await fetch(
-              "http://localhost:8080/forum/api/session/login",
+              `${API_BASE}/forum/api/session/login`,
               {
                 method: "POST",
                 headers: { "Content-Type": "application/json" },
@@ -16,7 +16,7 @@ document.addEventListener("DOMContentLoaded", () => {
   };
 
   // Populate categories
-  fetch("http://localhost:8080/forum/api/categories")
+  fetch(`${API_BASE}/forum/api/categories`)
     .then((res) => res.json())
     .then((categories) => {
       categorySelect.innerHTML =
@@ -38,10 +38,10 @@ document.addEventListener("DOMContentLoaded", () => {
     if (!title || !content || isNaN(categoryId)) return;
 
     try {
-      const res = await fetch("http://localhost:8080/forum/api/posts", {
+      const res = await fetch(`${API_BASE}/forum/api/posts`, {
         method: "POST",
         credentials: "include",
-        headers: { "Content-Type": "application/json" },
+        headers: { "Content-Type": "application/json", ...csrfHeaders() },
         body: JSON.stringify({
           title,
           content,
@@ -6,6 +6,13 @@ function countReactions(reactions = []) {
   };
 }
 
+// csrfHeaders returns the header the API expects on state-changing requests,
+// copied from the csrf_token cookie set at login
+function csrfHeaders() {
+  const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/);
+  return match ? { "X-CSRF-Token": decodeURIComponent(match[1]) } : {};
+}
+
 async function handleReaction(
   targetId,
   targetType,
@@ -14,11 +21,12 @@ async function handleReaction(
   dislikeBtn
 ) {
   try {
-    const res = await fetch("/api/react", {
+    const res = await fetch(`${API_BASE}/forum/api/reactions`, {
       method: "POST",
       credentials: "include",
       headers: {
         "Content-Type": "application/json",
+        ...csrfHeaders(),
       },
       body: JSON.stringify({
         target_id: targetId,
@@ -29,10 +37,9 @@ async function handleReaction(
 
     if (!res.ok) throw new Error("Failed to react");
 
-    const updatedReactions = await res.json();
-    const { likes, dislikes } = countReactions(updatedReactions);
-    likeBtn.querySelector(".like-count").textContent = likes;
-    dislikeBtn.querySelector(".dislike-count").textContent = dislikes;
+    const { counts } = await res.json();
+    likeBtn.querySelector(".like-count").textContent = counts.like;
+    dislikeBtn.querySelector(".dislike-count").textContent = counts.dislike;
   } catch (err) {
     console.error("Reaction failed:", err);
   }
@@ -256,7 +263,7 @@ document.addEventListener("DOMContentLoaded", async () => {
   }
 
   try {
-    const response = await fetch("http://localhost:8080/forum/api/guest");
+    const response = await fetch(`${API_BASE}/forum/api/guest`);
     if (!response.ok) throw new Error("Network response was not ok");
 
     data = await response.json(); // Assign to outer 'data'
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+
+	"forum/config"
+)
+
+// Redacted replaces the value of sensitive log attributes
+const Redacted = "[REDACTED]"
+
+// sensitiveLogKeys are attribute keys whose values never reach the log output,
+// wherever they appear in a group
+var sensitiveLogKeys = map[string]bool{
+	"password":         true,
+	"current_password": true,
+	"new_password":     true,
+	"password_hash":    true,
+	"hash":             true,
+	"token":            true,
+	"token_hash":       true,
+	"session_token":    true,
+	"csrf_token":       true,
+	"cookie":           true,
+	"authorization":    true,
+	"secret":           true,
+}
+
+// NewLogger creates the application logger writing to w. Level and format
+// come from FORUM_LOG_LEVEL and FORUM_LOG_FORMAT; values under sensitive keys
+// are replaced by [REDACTED].
+func NewLogger(w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       parseLogLevel(config.LogLevel),
+		ReplaceAttr: redactAttr,
+	}
+	if strings.EqualFold(config.LogFormat, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveLogKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+func parseLogLevel(value string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
@@ -0,0 +1,65 @@
+package utils_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"forum/config"
+	"forum/models"
+	"forum/utils"
+)
+
+const (
+	testPassword    = "hunter2-plaintext"
+	testNewPassword = "correct-horse-battery"
+	testHash        = "$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHQ$aGFzaGhhc2g"
+	testToken       = "session-token-0123456789abcdef"
+	testTokenHash   = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	testResetToken  = "reset-token-fedcba9876543210"
+)
+
+// Secrets must not reach the log output however they are logged: inside the
+// structs that carry them or as bare attributes under a sensitive key
+func TestLoggerRedactsSecrets(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			previous := config.LogFormat
+			config.LogFormat = format
+			defer func() { config.LogFormat = previous }()
+
+			var buf bytes.Buffer
+			logger := utils.NewLogger(&buf)
+
+			logger.Info("login", "login", models.UserLogin{Email: "alice@example.com", Password: testPassword})
+			logger.Info("register", "registration", models.UserRegistration{Username: "alice", Email: "alice@example.com", Password: testPassword})
+			logger.Info("auth", "auth", models.UserAuth{UserID: "user-1", PasswordHash: testHash})
+			logger.Info("session", "session", models.Session{ID: "session-1", UserID: "user-1", SessionID: testToken, TokenHash: testTokenHash})
+			logger.Info("change", "change", models.PasswordChange{CurrentPassword: testPassword, NewPassword: testNewPassword})
+			logger.Info("reset", "reset", models.PasswordReset{Token: testResetToken, NewPassword: testNewPassword})
+			logger.Info("attrs",
+				"password", testPassword,
+				"new_password", testNewPassword,
+				"password_hash", testHash,
+				"token", testToken,
+				"session_token", testToken,
+				"token_hash", testTokenHash,
+			)
+			logger.WithGroup("request").Info("grouped", "Password", testPassword, "TOKEN", testResetToken)
+
+			out := buf.String()
+			for _, secret := range []string{testPassword, testNewPassword, testHash, testToken, testTokenHash, testResetToken} {
+				if strings.Contains(out, secret) {
+					t.Errorf("log output contains secret %q:\n%s", secret, out)
+				}
+			}
+
+			// The non-secret parts are still logged
+			for _, want := range []string{"alice@example.com", "session-1", utils.Redacted} {
+				if !strings.Contains(out, want) {
+					t.Errorf("log output is missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
@@ -1,23 +1,126 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"forum/config"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword creates a bcrypt hash of the password
+// Stored hashes name their algorithm: bcrypt hashes start with "$2a$" (or
+// "$2b$", "$2y$"), argon2id hashes use the PHC string format
+// "$argon2id$v=19$m=<KiB>,t=<passes>,p=<threads>$<salt>$<hash>".
+const argon2idPrefix = "$argon2id$"
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// HashPassword hashes the password with the configured algorithm
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	if config.PasswordHashAlgorithm == "argon2id" {
+		return hashArgon2id(password)
+	}
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), config.BcryptCost)
 	return string(bytes), err
 }
 
-// CheckPasswordHash compares a bcrypt hashed password with a plain password
+// CheckPasswordHash compares a hashed password with a plain password
 func CheckPasswordHash(password, hash string) bool {
-	fmt.Println("Checking password:", password)
-	fmt.Println("Against hash:", hash)
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		return checkArgon2id(password, hash)
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
 
+// NeedsRehash reports whether a hash was made with another algorithm or other
+// parameters than currently configured
+func NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		if config.PasswordHashAlgorithm != "argon2id" {
+			return true
+		}
+		params, _, _, err := decodeArgon2id(hash)
+		return err != nil || params != currentArgon2Params()
+	}
 
+	if config.PasswordHashAlgorithm == "argon2id" {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != config.BcryptCost
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+func currentArgon2Params() argon2Params {
+	return argon2Params{
+		memory:  uint32(config.Argon2MemoryKiB),
+		time:    uint32(config.Argon2Time),
+		threads: uint8(config.Argon2Threads),
+	}
+}
+
+func hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := currentArgon2Params()
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, argon2KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func checkArgon2id(password, hash string) bool {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	other := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+// decodeArgon2id splits a PHC argon2id string into its parameters, salt and key
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errMalformedHash
+	}
+	// argon2.IDKey panics on zero passes or threads
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil || p.time == 0 || p.threads == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"testing"
+
+	"forum/config"
+)
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	previous := config.PasswordHashAlgorithm
+	config.PasswordHashAlgorithm = "argon2id"
+	defer func() { config.PasswordHashAlgorithm = previous }()
+
+	hash, err := HashPassword("password123")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if !CheckPasswordHash("password123", hash) {
+		t.Error("the password does not match its own hash")
+	}
+	if CheckPasswordHash("password124", hash) {
+		t.Error("a wrong password matches")
+	}
+	if NeedsRehash(hash) {
+		t.Error("a hash with the current parameters needs a rehash")
+	}
+}
+
+// Stored hashes with parameters argon2 cannot run are rejected instead of panicking
+func TestCheckPasswordHashRejectsInvalidArgon2Params(t *testing.T) {
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	for _, params := range []string{"m=65536,t=0,p=2", "m=65536,t=3,p=0", "m=65536,t=3,p=256"} {
+		hash := "$argon2id$v=19$" + params + "$" + salt + "$" + key
+		if CheckPasswordHash("password123", hash) {
+			t.Errorf("%s: password matched", params)
+		}
+		if !NeedsRehash(hash) {
+			t.Errorf("%s: NeedsRehash = false", params)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,13 +15,28 @@ func GenerateUUID() string {
 	return uuid.New().String()
 }
 
-// GenerateSessionToken creates a new session token/ID
-func GenerateSessionToken() string {
-	return GenerateUUID()
+// GenerateToken creates a new secret token (sessions, CSRF, emailed links)
+// from 256 random bits
+func GenerateToken() string {
+	b := make([]byte, 32)
+	rand.Read(b) // never returns an error since Go 1.24
+	return base64.RawURLEncoding.EncodeToString(b)
 }
 
-// CalculateSessionExpiry calculates the expiry time for a session
-// Default session lifetime is 24 hours
-func CalculateSessionExpiry() time.Time {
-	return time.Now().Add(24 * time.Hour)
-}
\ No newline at end of file
+// HashToken returns the SHA-256 digest of a token. Only the digest is
+// stored, so a copy of the database cannot be used to hijack sessions or
+// emailed links.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CalculateSessionExpiry calculates when a session last used at lastSeen
+// expires: after the idle timeout, but never later than its absolute expiry
+func CalculateSessionExpiry(lastSeen, absoluteExpiry time.Time, idle time.Duration) time.Time {
+	expiry := lastSeen.Add(idle)
+	if expiry.After(absoluteExpiry) {
+		return absoluteExpiry
+	}
+	return expiry
+}